@@ -0,0 +1,111 @@
+/*Package cmd handle all commands for a cli of quake-log.
+Copyright © 2020 Renato Biancalana da Silva <reesilva@pm.me>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/reesilva/quake-log/pkg/identity"
+	"github.com/reesilva/quake-log/pkg/input"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/spf13/cobra"
+)
+
+var aliasesFile string
+
+// aliasesCmd represents the aliases command
+var aliasesCmd = &cobra.Command{
+	Use:   "aliases",
+	Short: "Aliases suggests player names that are likely the same player",
+	Long: `With aliases command you will receive, as JSON, groups of player names found
+in the Quake 3 Arena Server log files that are likely the same player: names
+used from the same GUID or IP, when the server logs them, and similar names
+that never played the same match. Names already resolved to the same player
+by the --aliases file are not suggested again, so the suggestions can be
+reviewed and added to it:
+
+  players:
+    - name: Isgalamido
+      aliases: [Isga, isgalamido2]
+      guids: [8A9F03B1C2D4E5F6]
+      ips: [10.0.0.7]
+
+The same file is used by vadrigar, report and serve to merge the stats of
+every alias of a player under their canonical name.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ids, err := loadIdentities(aliasesFile)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		paths, err := input.Expand(logFiles, inputOrder)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		dialect, err := parseDialect(dialectName)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+
+		matches := []parser.Match{}
+		for _, series := range input.Group(paths) {
+			stream := parser.Stream{
+				Dialect: dialect,
+				OnMatchEnd: func(index int, match parser.Match) error {
+					matches = append(matches, match)
+					return nil
+				},
+			}
+			if err := readSeries(series, &stream, nil); err != nil {
+				log.Fatal(fmt.Errorf("%s: %w", series.Name, err))
+				os.Exit(1)
+			}
+		}
+
+		if err := writeReport(ids.Suggest(matches), outputFile); err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// loadIdentities loads the alias mapping file in path, or returns empty
+// Identities when path is empty.
+func loadIdentities(path string) (*identity.Identities, error) {
+	if path == "" {
+		return identity.New(nil)
+	}
+	return identity.Load(path)
+}
+
+func init() {
+	rootCmd.AddCommand(aliasesCmd)
+
+	aliasesCmd.Flags().StringArrayVarP(&logFiles, "log-file", "f", []string{}, "Path, glob pattern or directory of Quake 3 Arena Server logs files, or - for stdin. Can be repeated")
+	aliasesCmd.Flags().StringVar(&inputOrder, "order", input.ByName, `Order to read the log files: by "name", by "modtime", from the oldest modified, or by "time" of their first match`)
+	aliasesCmd.Flags().StringVarP(&aliasesFile, "aliases", "a", "", "YAML, TOML or JSON file mapping the names, GUIDs and IPs of each player to a canonical name")
+	aliasesCmd.Flags().StringVar(&dialectName, "dialect", "auto", `Log dialect: "auto", detected from each match, or one of baseq3, openarena, urt and quakelive`)
+	aliasesCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "Output file. If not set, will print as JSON in stdout")
+	err := aliasesCmd.MarkFlagRequired("log-file")
+	if err != nil {
+		log.Fatal(err)
+		os.Exit(1)
+	}
+}
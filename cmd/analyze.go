@@ -0,0 +1,170 @@
+/*Package cmd handle all commands for a cli of quake-log.
+Copyright © 2020 Renato Biancalana da Silva <reesilva@pm.me>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/reesilva/quake-log/pkg/analyze"
+	"github.com/reesilva/quake-log/pkg/input"
+	"github.com/reesilva/quake-log/pkg/quakelog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	minSeverity string
+	thresholds  = analyze.DefaultThresholds
+)
+
+// analyzeCmd represents the analyze command
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Analyze flags suspicious patterns in the matches of Quake 3 Arena Server logs",
+	Long: `With analyze command you will receive, as JSON, the matches of the Quake 3
+Arena Server logs with patterns that may point to cheating or to a tampered
+log, for admins to review before confirming their results. Each finding has
+the rule that flagged it and its severity, info, warning or critical:
+
+  kill_rate      more kills per minute than --max-kill-rate, critical at twice it
+  railgun_ratio  a share of kills by railgun above --max-railgun-ratio
+  spawn_kills    the same victim killed more than --max-spawn-kills times
+                 within --spawn-window of respawning
+  no_deaths      kills and no deaths over --long-session, as info
+  not_connected  kills by players that had left the match, or by client
+                 slots no player connected to, as critical
+
+Kill rates and railgun ratios are only judged for players with --min-kills.
+Bots are not flagged, but kills on them count. Only the matches with
+findings of --severity or above are reported. Lines that can't be parsed
+are skipped instead of stopping the analysis.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		paths, err := input.Expand(logFiles, inputOrder)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		ids, err := loadIdentities(aliasesFile)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		dialect, err := parseDialect(dialectName)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		severity, err := analyze.ParseSeverity(minSeverity)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+
+		out := os.Stdout
+		if outputFile != "" && format == "ndjson" {
+			out, err = os.Create(outputFile)
+			if err != nil {
+				log.Fatal(err)
+				os.Exit(1)
+			}
+			defer out.Close()
+		}
+		var encoder *json.Encoder
+		switch format {
+		case "json":
+		case "ndjson":
+			encoder = json.NewEncoder(out)
+		default:
+			log.Fatal(fmt.Errorf("Unknown format %q", format))
+			os.Exit(1)
+		}
+
+		flagged := []analyze.MatchFindings{}
+		onMatch := func(match quakelog.Match) error {
+			match.Match = ids.Apply(match.Match)
+			findings := analyze.Match(match, thresholds)
+			kept := []analyze.Finding{}
+			for _, finding := range findings.Findings {
+				if finding.Severity >= severity {
+					kept = append(kept, finding)
+				}
+			}
+			if len(kept) == 0 {
+				return nil
+			}
+			findings.Findings = kept
+			if encoder != nil {
+				return encoder.Encode(findings)
+			}
+			flagged = append(flagged, findings)
+			return nil
+		}
+		for _, series := range input.Group(paths) {
+			err := parseSeries(ctx, series, quakelog.Options{
+				Source:     series.Name,
+				Dialect:    dialect,
+				OnMatch:    onMatch,
+				SkipErrors: true,
+			}, nil)
+			if errors.Is(err, context.Canceled) {
+				log.Println("Interrupted")
+				os.Exit(130)
+			}
+			if err != nil {
+				log.Fatal(fmt.Errorf("%s: %w", series.Name, err))
+				os.Exit(1)
+			}
+		}
+		if encoder != nil {
+			os.Exit(0)
+		}
+
+		if err := writeReport(flagged, outputFile); err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(analyzeCmd)
+
+	analyzeCmd.Flags().StringArrayVarP(&logFiles, "log-file", "f", []string{}, "Path, glob pattern or directory of Quake 3 Arena Server logs files, or - for stdin. Can be repeated")
+	analyzeCmd.Flags().StringVar(&inputOrder, "order", input.ByName, `Order to read the log files: by "name", by "modtime", from the oldest modified, or by "time" of their first match`)
+	analyzeCmd.Flags().StringVarP(&aliasesFile, "aliases", "a", "", "YAML, TOML or JSON file mapping the names, GUIDs and IPs of each player to a canonical name")
+	analyzeCmd.Flags().StringVar(&dialectName, "dialect", "auto", `Log dialect: "auto", detected from each match, or one of baseq3, openarena, urt and quakelive`)
+	analyzeCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "Output file. If not set, will print as JSON in stdout")
+	analyzeCmd.Flags().StringVar(&format, "format", "json", `Output format: "json" or "ndjson", which writes each match in a line as soon as it ends`)
+	analyzeCmd.Flags().StringVar(&minSeverity, "severity", "info", `Report only the findings of this severity or above: "info", "warning" or "critical"`)
+	analyzeCmd.Flags().Float64Var(&thresholds.MaxKillsPerMinute, "max-kill-rate", thresholds.MaxKillsPerMinute, "Highest kills per minute of a player that is not flagged")
+	analyzeCmd.Flags().Float64Var(&thresholds.MaxRailgunRatio, "max-railgun-ratio", thresholds.MaxRailgunRatio, "Highest share of the kills of a player by railgun, from 0 to 1, that is not flagged")
+	analyzeCmd.Flags().IntVar(&thresholds.MinKills, "min-kills", thresholds.MinKills, "Kills a player needs for their kill rate and railgun ratio to be judged")
+	analyzeCmd.Flags().DurationVar(&thresholds.SpawnWindow, "spawn-window", thresholds.SpawnWindow, "How long after dying a player is still at spawn")
+	analyzeCmd.Flags().IntVar(&thresholds.MaxSpawnKills, "max-spawn-kills", thresholds.MaxSpawnKills, "Times a player can kill the same victim at spawn without being flagged")
+	analyzeCmd.Flags().DurationVar(&thresholds.LongSession, "long-session", thresholds.LongSession, "How long a player can kill without dying before being flagged")
+	err := analyzeCmd.MarkFlagRequired("log-file")
+	if err != nil {
+		log.Fatal(err)
+		os.Exit(1)
+	}
+}
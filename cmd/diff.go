@@ -0,0 +1,140 @@
+/*Package cmd handle all commands for a cli of quake-log.
+Copyright © 2020 Renato Biancalana da Silva <reesilva@pm.me>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/reesilva/quake-log/pkg/diff"
+	"github.com/reesilva/quake-log/pkg/input"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/quakelog"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD NEW",
+	Short: "Diff compares two Quake 3 Arena Server logs or two JSON reports",
+	Long: `With diff command you will receive, as JSON, what changed from the OLD to the
+NEW report: the matches added and removed and, for the matches in both, the
+kills that changed, by player and by mean of death, and the ranking of the
+players when it changed. It's useful to check a new version of the parser
+against an archive of reports or a log re-exported after a crash against
+the original one.
+
+OLD and NEW are each a JSON report saved by vadrigar or report, in the list
+or in the map layout, or a Quake 3 Arena Server log, which is parsed like
+vadrigar does, so a log can be compared to a report too. They can be glob
+patterns or directories of logs as well. Matches are paired by ID and then
+by map and start time, so a match parsed differently is still paired.
+Reports in the map layout only have the index of their matches, by which
+they are paired.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		ids, err := loadIdentities(aliasesFile)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		dialect, err := parseDialect(dialectName)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		options := []quakelog.ReportOption{quakelog.WithIdentities(ids)}
+		if meanOfDeath {
+			options = append(options, quakelog.WithMeansOfDeath())
+		}
+
+		sides := make([][]output.MatchEntry, len(args))
+		for i, path := range args {
+			sides[i], err = loadEntries(ctx, path, dialect, options)
+			if errors.Is(err, context.Canceled) {
+				log.Println("Interrupted")
+				os.Exit(130)
+			}
+			if err != nil {
+				log.Fatal(fmt.Errorf("%s: %w", path, err))
+				os.Exit(1)
+			}
+		}
+
+		if err := writeReport(diff.Reports(sides[0], sides[1]), outputFile); err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// loadEntries returns the matches of the JSON report in path or, when it's
+// not a report, of the logs it expands to, reported with options.
+func loadEntries(ctx context.Context, path string, dialect parser.Dialect, options []quakelog.ReportOption) ([]output.MatchEntry, error) {
+	paths, err := input.Expand([]string{path}, inputOrder)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 1 {
+		file, err := input.Open(paths[0])
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader := bufio.NewReader(file)
+		isReport, err := diff.IsReport(reader)
+		if err != nil {
+			return nil, err
+		}
+		if isReport {
+			return diff.ReadReport(reader)
+		}
+	}
+
+	report := quakelog.NewReport(options...)
+	for _, series := range input.Group(paths) {
+		err := parseSeries(ctx, series, quakelog.Options{
+			Source:  series.Name,
+			Dialect: dialect,
+			OnMatch: func(match quakelog.Match) error {
+				report.Add(match)
+				return nil
+			},
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return report.Entries(), nil
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().BoolVarP(&meanOfDeath, "mean-of-death", "m", false, "Compare the kills by mean of death of the logs too")
+	diffCmd.Flags().StringVar(&inputOrder, "order", input.ByName, `Order to read the log files: by "name", by "modtime", from the oldest modified, or by "time" of their first match`)
+	diffCmd.Flags().StringVarP(&aliasesFile, "aliases", "a", "", "YAML, TOML or JSON file mapping the names, GUIDs and IPs of each player to a canonical name")
+	diffCmd.Flags().StringVar(&dialectName, "dialect", "auto", `Log dialect: "auto", detected from each match, or one of baseq3, openarena, urt and quakelive`)
+	diffCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "Output file. If not set, will print as JSON in stdout")
+}
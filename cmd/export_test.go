@@ -0,0 +1,12 @@
+package cmd
+
+// BindConfig sets the flags of a command from the config like every command
+// does before it runs.
+var BindConfig = bindConfig
+
+// InitConfig reads the config file in path and the environment like --config
+// does.
+func InitConfig(path string) {
+	cfgFile = path
+	initConfig()
+}
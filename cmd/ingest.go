@@ -0,0 +1,126 @@
+/*Package cmd handle all commands for a cli of quake-log.
+Copyright © 2020 Renato Biancalana da Silva <reesilva@pm.me>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/reesilva/quake-log/pkg/checkpoint"
+	"github.com/reesilva/quake-log/pkg/input"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	databaseFile string
+	fullIngest   bool
+)
+
+// ingestCmd represents the ingest command
+var ingestCmd = &cobra.Command{
+	Use:   "ingest",
+	Short: "Ingest saves the matches of Quake 3 Arena Server log files in a SQLite database",
+	Long: `With ingest command you will parse Quake 3 Arena Server log files and save their
+matches, their settings, players, kills, items and chat in a SQLite database. Matches
+already in the database are skipped, so the same log can be ingested again
+safely. Use the report command to read them back.
+
+Where the log file was read up to, and the match being played at that point,
+is also saved, so the next run only parses what was written since then, even
+if the log file was rotated or truncated in between. The match being played
+is only saved once it ends.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := store.Open(databaseFile)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		paths, err := input.Expand(logFiles, inputOrder)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+
+		dialect, err := parseDialect(dialectName)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+
+		saved, skipped := 0, 0
+		for _, series := range input.Group(paths) {
+			if err := ingestSeries(db, series, dialect, &saved, &skipped); err != nil {
+				log.Fatal(fmt.Errorf("%s: %w", series.Name, err))
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("Ingested %d new matches, %d already in the database\n", saved, skipped)
+	},
+}
+
+// ingestSeries saves in db the matches of series that ended since its
+// checkpoint, counting how many were saved or skipped.
+func ingestSeries(db *store.Store, series input.Series, dialect parser.Dialect, saved *int, skipped *int) error {
+	stream := parser.Stream{
+		Dialect: dialect,
+		OnMatchEnd: func(index int, match parser.Match) error {
+			ok, err := db.SaveMatch(series.Name, index, match)
+			if ok {
+				*saved++
+			} else {
+				*skipped++
+			}
+			return err
+		},
+	}
+	key, resumable, err := checkpointKey(series)
+	if err != nil {
+		return err
+	}
+	if !resumable {
+		return readSeries(series, &stream, nil)
+	}
+	cp := checkpoint.Checkpoint{}
+	if !fullIngest {
+		if cp, err = db.Checkpoint(key); err != nil {
+			return err
+		}
+	}
+	if err := readSeries(series, &stream, &cp); err != nil {
+		return err
+	}
+	return db.SaveCheckpoint(key, cp)
+}
+
+func init() {
+	rootCmd.AddCommand(ingestCmd)
+
+	ingestCmd.Flags().StringArrayVarP(&logFiles, "log-file", "f", []string{}, "Path, glob pattern or directory of Quake 3 Arena Server logs files, or - for stdin. Can be repeated")
+	ingestCmd.Flags().StringVar(&inputOrder, "order", input.ByName, `Order to read the log files: by "name", by "modtime", from the oldest modified, or by "time" of their first match`)
+	ingestCmd.Flags().StringVarP(&databaseFile, "database", "d", "quake-log.db", "Path for the SQLite database")
+	ingestCmd.Flags().StringVar(&dialectName, "dialect", "auto", `Log dialect: "auto", detected from each match, or one of baseq3, openarena, urt and quakelive`)
+	ingestCmd.Flags().BoolVar(&fullIngest, "full", false, "Ignore the saved checkpoint and parse the whole log file again")
+	err := ingestCmd.MarkFlagRequired("log-file")
+	if err != nil {
+		log.Fatal(err)
+		os.Exit(1)
+	}
+}
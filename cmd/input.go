@@ -0,0 +1,144 @@
+/*Package cmd handle all commands for a cli of quake-log.
+Copyright © 2020 Renato Biancalana da Silva <reesilva@pm.me>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"path/filepath"
+
+	"github.com/reesilva/quake-log/pkg/checkpoint"
+	"github.com/reesilva/quake-log/pkg/input"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/quakelog"
+)
+
+// dialectName is the --dialect flag of the commands that parse logs.
+var dialectName string
+
+// parseDialect returns the dialect called name, or nil when it's auto,
+// so the parser detects the dialect of each match.
+func parseDialect(name string) (parser.Dialect, error) {
+	if name == "" || name == "auto" {
+		return nil, nil
+	}
+	return parser.LookupDialect(name)
+}
+
+// readLines reads the whole file in path calling handle for every line.
+func readLines(path string, handle func(line string) error) error {
+	file, err := input.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if err := handle(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// checkpointKey returns the key the checkpoint of series is saved with,
+// or false when the series can't be resumed, because its newest file is
+// stdin or is compressed.
+func checkpointKey(series input.Series) (string, bool, error) {
+	live := series.Files[len(series.Files)-1]
+	if live == input.Stdin {
+		return "", false, nil
+	}
+	compressed, err := input.Compressed(live)
+	if err != nil || compressed {
+		return "", false, err
+	}
+	key, err := filepath.Abs(live)
+	return key, err == nil, err
+}
+
+// lineParser parses a log line by line, like parser.Stream and
+// parser.Parallel.
+type lineParser interface {
+	ParseLine(line string) error
+	Close() error
+}
+
+// readAll parses every file of series, oldest first, and closes p even when
+// a file fails, so its workers are stopped, returning the first error.
+func readAll(series input.Series, p lineParser) (err error) {
+	defer func() {
+		if closeErr := p.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	for _, path := range series.Files {
+		if err := readLines(path, p.ParseLine); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSeries parses every file of series, oldest first, as a single log,
+// until ctx is done. The bytes read from the files are added to count, if
+// it's not nil.
+func parseSeries(ctx context.Context, series input.Series, opts quakelog.Options, count *int64) error {
+	p := quakelog.NewParser(opts)
+	for _, path := range series.Files {
+		file, err := input.OpenCounted(path, count)
+		if err != nil {
+			p.Close()
+			return err
+		}
+		err = p.ReadContext(ctx, file)
+		file.Close()
+		if err != nil {
+			p.Close()
+			return err
+		}
+	}
+	_, _, err := p.Close()
+	return err
+}
+
+// readSeries parses the files of series, oldest first, through stream.
+// When cp is nil the whole series is read and stream is closed at the end.
+// Otherwise the newest file is read from cp, which is updated, keeping the
+// match being played in it, and the rotated files are only read when cp
+// is empty, since they were read before being rotated, up to cp, and
+// checkpoint.Read reads the rest of the one cp was taken from.
+func readSeries(series input.Series, stream *parser.Stream, cp *checkpoint.Checkpoint) error {
+	rotated, live := series.Files[:len(series.Files)-1], series.Files[len(series.Files)-1]
+	if cp == nil {
+		return readAll(series, stream)
+	}
+	if *cp == (checkpoint.Checkpoint{}) {
+		for _, path := range rotated {
+			if err := readLines(path, stream.ParseLine); err != nil {
+				return err
+			}
+		}
+		cp.State = stream.State()
+	}
+	next, err := checkpoint.Read(live, *cp, stream)
+	if err != nil {
+		return err
+	}
+	*cp = next
+	return nil
+}
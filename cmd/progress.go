@@ -0,0 +1,143 @@
+/*Package cmd handle all commands for a cli of quake-log.
+Copyright © 2020 Renato Biancalana da Silva <reesilva@pm.me>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/input"
+	"github.com/reesilva/quake-log/pkg/quakelog"
+)
+
+const (
+	// _progressMinSize is the size of the logs from which a progress bar
+	// is shown, since smaller ones are parsed in a blink.
+	_progressMinSize = 16 << 20
+	_progressWidth   = 30
+	_progressEvery   = 200 * time.Millisecond
+)
+
+// progressBar draws on the terminal how much of the log files was parsed.
+// Its methods do nothing on a nil progressBar, which is what newProgressBar
+// returns when the bar shouldn't be shown.
+type progressBar struct {
+	out   io.Writer
+	total int64
+	// read is the count of bytes read from the files, kept by
+	// input.OpenCounted.
+	read int64
+	// done is the progress of the series already parsed, and current the
+	// one of the series being parsed.
+	done    quakelog.Progress
+	current quakelog.Progress
+	drawn   time.Time
+}
+
+// newProgressBar returns a progress bar for the log files in paths, if
+// stderr is a terminal and they are large enough.
+func newProgressBar(paths []string) *progressBar {
+	if info, err := os.Stderr.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	var total int64
+	for _, path := range paths {
+		if path == input.Stdin {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil
+		}
+		total += info.Size()
+	}
+	if total < _progressMinSize {
+		return nil
+	}
+	return &progressBar{out: os.Stderr, total: total}
+}
+
+// counter returns where the bytes read from the files must be counted.
+func (b *progressBar) counter() *int64 {
+	if b == nil {
+		return nil
+	}
+	return &b.read
+}
+
+// update sets the progress of the series being parsed.
+func (b *progressBar) update(progress quakelog.Progress) {
+	if b == nil {
+		return
+	}
+	b.current = progress
+	if time.Since(b.drawn) >= _progressEvery {
+		b.draw()
+	}
+}
+
+// next adds the progress of the series that was parsed to the total.
+func (b *progressBar) next() {
+	if b == nil {
+		return
+	}
+	b.done.Lines += b.current.Lines
+	b.done.Matches += b.current.Matches
+	b.current = quakelog.Progress{}
+}
+
+// finish draws the bar one last time and moves to the next line, so it's
+// not overwritten by what is written next.
+func (b *progressBar) finish() {
+	if b == nil {
+		return
+	}
+	b.draw()
+	fmt.Fprintln(b.out)
+}
+
+func (b *progressBar) draw() {
+	b.drawn = time.Now()
+	ratio := float64(b.read) / float64(b.total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * _progressWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", _progressWidth-filled)
+	if filled < _progressWidth {
+		bar = bar[:filled] + ">" + bar[filled+1:]
+	}
+	fmt.Fprintf(b.out, "\r[%s] %3.0f%%  %s/%s  %d lines  %d matches ",
+		bar, ratio*100, formatBytes(b.read), formatBytes(b.total),
+		b.done.Lines+b.current.Lines, b.done.Matches+b.current.Matches)
+}
+
+// formatBytes formats a size in bytes with a binary unit, like 12.3 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,103 @@
+/*Package cmd handle all commands for a cli of quake-log.
+Copyright © 2020 Renato Biancalana da Silva <reesilva@pm.me>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/reesilva/quake-log/pkg/quakelog"
+	"github.com/reesilva/quake-log/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report outputs the matches saved by ingest in a SQLite database",
+	Long: `With report command you will receive, in stdout or in a file, the same JSON
+report of vadrigar, but built from the matches saved by the ingest command
+in a SQLite database instead of parsing the log files again. Matches can be
+filtered with --where, players merged with --aliases, bots left out with
+--exclude-bots and timelines and awards added with --timeline and --awards,
+in the same way of vadrigar, and --format text writes the matches for people
+to read instead of JSON. Matches ingested before kills had a time have their kills at the
+start of the match in the timeline.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := store.Open(databaseFile)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		stored, err := db.Matches()
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		ids, err := loadIdentities(aliasesFile)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		filter, err := parseWhere(where)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+
+		options, err := reportOptions(ids, filter)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		report := quakelog.NewReport(options...)
+		for _, m := range stored {
+			report.Add(quakelog.Match{Source: m.Source, Index: m.Index, ID: m.ID, Match: m.Match})
+		}
+		switch format {
+		case "json":
+			writeLayout(report, layout, outputFile)
+		case "text":
+			if err := writeText(report.Entries(), outputFile); err != nil {
+				log.Fatal(err)
+				os.Exit(1)
+			}
+		default:
+			log.Fatal(fmt.Errorf("Unknown format %q", format))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().StringVarP(&databaseFile, "database", "d", "quake-log.db", "Path for the SQLite database")
+	reportCmd.Flags().BoolVarP(&meanOfDeath, "mean-of-death", "m", false, "Enable or disable logs of deaths by mean")
+	reportCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "Output file. If not set, will print as JSON in stdout")
+	reportCmd.Flags().StringVarP(&where, "where", "w", "", `Report only the matches that satisfy a query, like "map = q3dm17 and kills > 50"`)
+	reportCmd.Flags().StringVarP(&aliasesFile, "aliases", "a", "", "YAML, TOML or JSON file mapping the names, GUIDs and IPs of each player to a canonical name")
+	reportCmd.Flags().BoolVar(&excludeBots, "exclude-bots", false, "Leave out the bots and the kills they made or suffered")
+	reportCmd.Flags().StringVar(&timelineMode, "timeline", "", `Add the timeline of the scores to each match, sampled every "minute" or after every "event". Needs the list layout`)
+	reportCmd.Flags().StringVar(&awardsFile, "awards", "", "YAML, TOML or JSON file with the rules of the awards won in each match. Needs the list layout")
+	reportCmd.Flags().StringToIntVar(&scoring, "scoring", nil, `Add the scores of the players to each match, by the points of a "kill", a "suicide" and a death by the "world", like kill=1,world=-1. Needs the list layout`)
+	reportCmd.Flags().StringVar(&serverName, "server-name", "", "Name of the server the logs are from, added to each match of the list layout")
+	reportCmd.Flags().StringVar(&format, "format", "json", `Output format: "json" or "text", which writes each match for people to read`)
+	reportCmd.Flags().StringVarP(&layout, "layout", "l", "map", `Layout of the report: "map" keyed by game_N or "list" of ordered matches`)
+}
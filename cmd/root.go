@@ -18,15 +18,58 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/reesilva/quake-log/pkg/output"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 )
 
+var (
+	cfgFile  string
+	modTable map[string]string
+)
+
+// _envKeys turns the keys of the config into the names of their
+// environment variables, after the QUAKELOG_ prefix.
+var _envKeys = strings.NewReplacer(".", "_", "-", "_")
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "quake-log",
 	Short: "Quake Log is a log parser to Quake 3 Arena Server logs",
-	Long:  `The Quake-Log applications ban be used to parse all logs from Quake 3 Arena server and output it to you.`,
+	Long: `The Quake-Log applications ban be used to parse all logs from Quake 3 Arena server and output it to you.
+
+The default of every flag can be set in a .quake-log config file, in YAML, TOML
+or JSON, searched in the working directory and then in the home directory, or
+in the file given with --config. Keys are the flag names, at the top level for
+all commands or under the name of a command for that command only:
+
+  format: ndjson
+  log-file:
+    - /var/log/quake3/games.log
+  server-name: Code Miner Server
+  scoring:
+    kill: 1
+    world: -1
+  mod-table:
+    29: MOD_GRAPPLE_HOOK
+  serve:
+    addr: :9000
+
+Environment variables QUAKELOG_<FLAG> and QUAKELOG_<COMMAND>_<FLAG>, like
+QUAKELOG_FORMAT or QUAKELOG_SERVE_ADDR, take precedence over the config file
+and flags passed in the command line take precedence over both.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := bindConfig(cmd); err != nil {
+			return err
+		}
+		return setMeansOfDeath(modTable)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -37,3 +80,106 @@ func Execute() {
 		os.Exit(1)
 	}
 }
+
+func init() {
+	cobra.OnInitialize(initConfig)
+
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file. If not set, .quake-log in the working or home directory is used when it exists")
+	rootCmd.PersistentFlags().StringToStringVar(&modTable, "mod-table", nil, "Names of the means of death by their numbers in the log, for the ones added by mods or to rename known ones, like 29=MOD_GRAPPLE_HOOK")
+}
+
+// initConfig reads the config file and the QUAKELOG_ environment variables.
+func initConfig() {
+	if cfgFile != "" {
+		viper.SetConfigFile(cfgFile)
+	} else {
+		viper.AddConfigPath(".")
+		if home, err := homedir.Dir(); err == nil {
+			viper.AddConfigPath(home)
+		}
+		viper.SetConfigName(".quake-log")
+	}
+	viper.SetEnvPrefix("quakelog")
+	viper.SetEnvKeyReplacer(_envKeys)
+	viper.AutomaticEnv()
+
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok || cfgFile != "" {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+}
+
+// bindConfig sets the flags of cmd that were not passed in the command line
+// to their values in the environment and then in the config file, looking
+// first under the name of cmd in each. Lists set the flag once for each of
+// their values and maps once for each key, as key=value.
+func bindConfig(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "config" || flag.Name == "help" {
+			return
+		}
+		key, ok := configKey(cmd.Name()+"."+flag.Name, flag.Name)
+		if !ok {
+			return
+		}
+		for _, value := range configValues(viper.Get(key)) {
+			if err = cmd.Flags().Set(flag.Name, fmt.Sprint(value)); err != nil {
+				err = fmt.Errorf("Invalid config for %s: %w", key, err)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// configKey returns the first of keys set in the environment or, when none
+// is, the first set in the config file.
+func configKey(keys ...string) (string, bool) {
+	for _, key := range keys {
+		if _, ok := os.LookupEnv("QUAKELOG_" + strings.ToUpper(_envKeys.Replace(key))); ok {
+			return key, true
+		}
+	}
+	for _, key := range keys {
+		if viper.IsSet(key) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// configValues returns the values a flag is set to by a value of the config.
+func configValues(value interface{}) []interface{} {
+	switch value := value.(type) {
+	case []interface{}:
+		return value
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, key+"="+fmt.Sprint(value[key]))
+		}
+		return values
+	}
+	return []interface{}{value}
+}
+
+// setMeansOfDeath names the means of death of table, keyed by their
+// numbers in the log.
+func setMeansOfDeath(table map[string]string) error {
+	for key, name := range table {
+		id, err := strconv.Atoi(key)
+		if err != nil || id < 0 {
+			return fmt.Errorf("Invalid mean of death %q in --mod-table, expected a number", key)
+		}
+		output.SetMeanOfDeath(id, name)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package cmd_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/reesilva/quake-log/cmd"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		env     map[string]string
+		args    []string
+		format  string
+		files   []string
+		scoring map[string]int
+		err     error
+	}{
+		{
+			name:   "Defaults",
+			args:   []string{"-f", "games.log"},
+			format: "json",
+			files:  []string{"games.log"},
+		},
+		{
+			name:   "Config file over the defaults",
+			config: "format: ndjson\n",
+			args:   []string{"-f", "games.log"},
+			format: "ndjson",
+			files:  []string{"games.log"},
+		},
+		{
+			name:   "Environment over the config file",
+			config: "format: ndjson\n",
+			env:    map[string]string{"QUAKELOG_FORMAT": "text"},
+			args:   []string{"-f", "games.log"},
+			format: "text",
+			files:  []string{"games.log"},
+		},
+		{
+			name:   "Flag over the environment",
+			config: "format: ndjson\n",
+			env:    map[string]string{"QUAKELOG_FORMAT": "text"},
+			args:   []string{"-f", "games.log", "--format", "json"},
+			format: "json",
+			files:  []string{"games.log"},
+		},
+		{
+			name:   "Section of the command over the top level",
+			config: "format: ndjson\nvadrigar:\n  format: text\n",
+			args:   []string{"-f", "games.log"},
+			format: "text",
+			files:  []string{"games.log"},
+		},
+		{
+			name:   "Section of another command",
+			config: "serve:\n  format: ndjson\n",
+			args:   []string{"-f", "games.log"},
+			format: "json",
+			files:  []string{"games.log"},
+		},
+		{
+			name:   "Environment of the command over the environment",
+			env:    map[string]string{"QUAKELOG_FORMAT": "ndjson", "QUAKELOG_VADRIGAR_FORMAT": "text"},
+			args:   []string{"-f", "games.log"},
+			format: "text",
+			files:  []string{"games.log"},
+		},
+		{
+			name:   "Environment over the section of the command",
+			config: "vadrigar:\n  format: text\n",
+			env:    map[string]string{"QUAKELOG_FORMAT": "ndjson"},
+			args:   []string{"-f", "games.log"},
+			format: "ndjson",
+			files:  []string{"games.log"},
+		},
+		{
+			name:   "Required flag from the config file",
+			config: "log-file:\n  - games.log\n  - qconsole.log\n",
+			format: "json",
+			files:  []string{"games.log", "qconsole.log"},
+		},
+		{
+			name:   "Required flag from the environment",
+			env:    map[string]string{"QUAKELOG_LOG_FILE": "games.log"},
+			format: "json",
+			files:  []string{"games.log"},
+		},
+		{
+			name: "Required flag not set",
+			err:  errors.New(`required flag(s) "log-file" not set`),
+		},
+		{
+			name:    "Map from the config file",
+			config:  "scoring:\n  kill: 1\n  world: -1\n",
+			args:    []string{"-f", "games.log"},
+			format:  "json",
+			files:   []string{"games.log"},
+			scoring: map[string]int{"kill": 1, "world": -1},
+		},
+		{
+			name:    "Map flag over the config file",
+			config:  "scoring:\n  kill: 1\n  world: -1\n",
+			args:    []string{"-f", "games.log", "--scoring", "kill=2"},
+			format:  "json",
+			files:   []string{"games.log"},
+			scoring: map[string]int{"kill": 2},
+		},
+		{
+			name:   "Invalid config",
+			config: "scoring: many\n",
+			args:   []string{"-f", "games.log"},
+			err:    errors.New(`Invalid config for scoring: invalid argument "many" for "--scoring" flag: many must be formatted as key=value`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "config.yaml")
+			if err := ioutil.WriteFile(path, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range tt.env {
+				os.Setenv(key, value)
+				defer os.Unsetenv(key)
+			}
+			viper.Reset()
+			cmd.InitConfig(path)
+
+			var (
+				format  string
+				files   []string
+				scoring map[string]int
+			)
+			root := &cobra.Command{
+				Use:           "quake-log",
+				SilenceErrors: true,
+				SilenceUsage:  true,
+				PersistentPreRunE: func(c *cobra.Command, args []string) error {
+					return cmd.BindConfig(c)
+				},
+			}
+			vadrigar := &cobra.Command{Use: "vadrigar", Run: func(c *cobra.Command, args []string) {}}
+			vadrigar.Flags().StringVar(&format, "format", "json", "")
+			vadrigar.Flags().StringArrayVarP(&files, "log-file", "f", []string{}, "")
+			vadrigar.Flags().StringToIntVar(&scoring, "scoring", nil, "")
+			if err := vadrigar.MarkFlagRequired("log-file"); err != nil {
+				t.Fatal(err)
+			}
+			root.AddCommand(vadrigar)
+			root.SetArgs(append([]string{"vadrigar"}, tt.args...))
+
+			err = root.Execute()
+			if tt.err != nil {
+				assert.EqualError(t, err, tt.err.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.format, format)
+			assert.Equal(t, tt.files, files)
+			assert.Equal(t, tt.scoring, scoring)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+/*Package cmd handle all commands for a cli of quake-log.
+Copyright © 2020 Renato Biancalana da Silva <reesilva@pm.me>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/input"
+	"github.com/reesilva/quake-log/pkg/metrics"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/server"
+	"github.com/reesilva/quake-log/pkg/tail"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listenAddr string
+	watchLogs  bool
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve parses Quake 3 Arena Server log files and serves them as a JSON API",
+	Long: `With serve command you will parse one or more Quake 3 Arena Server log files
+and serve the matches, the players stats, a leaderboard and the weapons stats
+through a JSON API. With --watch the log files keep being followed, the
+API is updated as the matches are played and every kill, connect, disconnect,
+match start and match end is pushed to the clients of the live feed.
+Prometheus metrics of the servers activity are served on /metrics. Players
+are merged under their canonical names with --aliases and bots are left out
+of the stats with --exclude-bots.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		paths, err := input.Expand(logFiles, inputOrder)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+
+		ids, err := loadIdentities(aliasesFile)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		dialect, err := parseDialect(dialectName)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		api := server.New()
+		api.Identities = ids
+		api.ExcludeBots = excludeBots
+		stats := metrics.New()
+		for _, series := range input.Group(paths) {
+			source := series.Name
+			stream := parser.Stream{
+				Dialect: dialect,
+				OnMatchEnd: func(index int, match parser.Match) error {
+					api.Put(source, index, match)
+					return nil
+				},
+				OnEvent: func(event parser.Event) error {
+					api.Publish(source, event)
+					stats.Event(source, event)
+					return nil
+				},
+			}
+			handle := func(line string) error {
+				err := stream.ParseLine(line)
+				stats.Line(source, err)
+				if err != nil {
+					log.Println(source, err)
+					return nil
+				}
+				if index, match, ok := stream.Current(); ok && watchLogs {
+					api.Put(source, index, match)
+				}
+				return nil
+			}
+
+			_, followable, err := checkpointKey(series)
+			if err != nil {
+				log.Fatal(err)
+				os.Exit(1)
+			}
+			files := series.Files
+			if watchLogs && followable {
+				files = files[:len(files)-1]
+			}
+			for _, path := range files {
+				if err := readLines(path, handle); err != nil {
+					log.Fatal(err)
+					os.Exit(1)
+				}
+			}
+			if !watchLogs || !followable {
+				if err := stream.Close(); err != nil {
+					log.Fatal(err)
+					os.Exit(1)
+				}
+				continue
+			}
+
+			live := series.Files[len(series.Files)-1]
+			go func() {
+				opts := tail.Options{
+					PollInterval: pollInterval,
+					OnProgress: func(offset int64) {
+						stats.Progress(live, offset)
+					},
+				}
+				if err := tail.Follow(ctx, live, opts, handle); err != nil {
+					log.Println(live, err)
+				}
+			}()
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", stats.Handler())
+		mux.Handle("/", api.Handler())
+		httpServer := &http.Server{
+			Addr:    listenAddr,
+			Handler: mux,
+		}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer shutdownCancel()
+			_ = httpServer.Shutdown(shutdownCtx)
+		}()
+		log.Printf("Serving %d log file(s) on %s", len(paths), listenAddr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringArrayVarP(&logFiles, "log-file", "f", []string{}, "Path, glob pattern or directory of Quake 3 Arena Server logs files, or - for stdin. Can be repeated")
+	serveCmd.Flags().StringVar(&inputOrder, "order", input.ByName, `Order to read the log files: by "name", by "modtime", from the oldest modified, or by "time" of their first match`)
+	serveCmd.Flags().StringVarP(&listenAddr, "addr", "a", ":8080", "Address the API will listen on")
+	serveCmd.Flags().BoolVarP(&watchLogs, "watch", "w", false, "Keep following the log files and update the API as they grow")
+	serveCmd.Flags().StringVar(&aliasesFile, "aliases", "", "YAML, TOML or JSON file mapping the names, GUIDs and IPs of each player to a canonical name")
+	serveCmd.Flags().BoolVar(&excludeBots, "exclude-bots", false, "Leave out the bots and the kills they made or suffered")
+	serveCmd.Flags().StringVar(&dialectName, "dialect", "auto", `Log dialect: "auto", detected from each match, or one of baseq3, openarena, urt and quakelive`)
+	serveCmd.Flags().DurationVar(&pollInterval, "interval", tail.DefaultPollInterval, "How often to check the log files for new lines when watching")
+	err := serveCmd.MarkFlagRequired("log-file")
+	if err != nil {
+		log.Fatal(err)
+		os.Exit(1)
+	}
+}
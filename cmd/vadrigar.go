@@ -16,23 +16,45 @@ limitations under the License.
 package cmd
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
-	"regexp"
+	"runtime"
+	"time"
 
+	"github.com/reesilva/quake-log/pkg/awards"
+	"github.com/reesilva/quake-log/pkg/checkpoint"
+	"github.com/reesilva/quake-log/pkg/identity"
+	"github.com/reesilva/quake-log/pkg/input"
 	"github.com/reesilva/quake-log/pkg/output"
 	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/quakelog"
+	"github.com/reesilva/quake-log/pkg/query"
 	"github.com/spf13/cobra"
 )
 
 var (
-	meanOfDeath bool
-	logFile     string
-	outputFile  string
+	meanOfDeath    bool
+	logFile        string
+	logFiles       []string
+	inputOrder     string
+	outputFile     string
+	layout         string
+	format         string
+	checkpointFile string
+	where          string
+	excludeBots    bool
+	workers        int
+	partial        bool
+	noProgress     bool
+	timelineMode   string
+	awardsFile     string
+	scoring        map[string]int
+	serverName     string
 )
 
 // vadrigarCmd represents the vadrigar command
@@ -41,69 +63,322 @@ var vadrigarCmd = &cobra.Command{
 	Short: "Vadrigar will parse a file that contains logs for a specific Quake 3 Arena Server",
 	Long: `With vadrigar command you will parse an entire Quake 3 Arena servers and receive,
 in stdout or in a file, the logs for each game structured in JSON. You will also be able to 
-activate an option to show to you the number of deaths by mean in each game.`,
+activate an option to show to you the number of deaths by mean in each game.
+
+Many log files can be parsed at once by repeating --log-file, which also accepts
+glob patterns, directories, that are read recursively, and - for stdin. The
+report has the matches of all of them, each one with the file it came from.
+Logs compressed with gzip, bzip2, xz or zstd are decompressed on the fly and
+rotated logs, like games.log.2.gz, games.log.1 and games.log, are read in
+order as a single log, so matches are not split by the rotation.
+
+Only the matches that satisfy --where are reported, for example:
+
+  --where 'map = q3dm17 and kills > 50'
+  --where 'killer = Isgalamido and weapon = railgun'
+  --where 'player = Mocinha and start >= 10:00 and start < 30:00'
+
+The fields are map, source, index, kills, players, player and start, plus
+killer, victim and weapon, which keep only the kills that satisfy the query.
+
+With --timeline each match has the scores of every player over time, sampled
+every minute or after every kill, the moments a player took the lead and
+when the winner took it for good. With --awards each match has the awards
+won by its players, like "Most suicides" or "Survivor", by the rules of a
+YAML, TOML or JSON file:
+
+  awards:
+    - name: Survivor
+      description: Fewest deaths
+      stat: deaths
+      rank: fewest
+    - name: Untouchable
+      stat: kills
+      conditions: [deaths = 0, kills >= 5]
+
+The stats are kills, deaths, suicides, nemesis, the most kills of the same
+victim, and kills_<weapon>, like kills_gauntlet. Awards go to every player
+tied with the most, or the fewest, of the stat among the players that
+satisfy all the conditions.
+
+With --aliases the players are reported by their canonical names, merging
+the stats of all their aliases. See the aliases command for its format.
+Bots are listed apart in each match and --exclude-bots leaves them, and the
+kills they made or suffered, out of the report.
+
+Large logs can be parsed faster with --workers, which parses many matches at
+the same time and reports them in the same order, with the same results.
+While large logs are parsed, a progress bar is shown when stderr is a
+terminal. Ctrl-C stops parsing without writing the report, unless --partial
+is set, which writes the report of the matches that ended before it.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		file, err := os.Open(logFile)
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		paths, err := input.Expand(logFiles, inputOrder)
 		if err != nil {
 			log.Fatal(err)
 			os.Exit(1)
 		}
-		defer file.Close()
 
-		scanner := bufio.NewScanner(file)
-		matches := []parser.Match{}
-		r, err := regexp.Compile(`\d+:\d+ (\w+): (.*)`)
+		ids, err := loadIdentities(aliasesFile)
 		if err != nil {
 			log.Fatal(err)
 			os.Exit(1)
 		}
-		for scanner.Scan() {
-			matched := r.MatchString(scanner.Text())
-			if !matched {
-				continue
-			}
-			parseErr := parser.ParseLine(len(matches)-1, &matches, scanner.Text())
-			if parseErr != nil {
-				log.Fatal(parseErr)
-				os.Exit(1)
-			}
-		}
-
-		if err := scanner.Err(); err != nil {
+		filter, err := parseWhere(where)
+		if err != nil {
 			log.Fatal(err)
 			os.Exit(1)
 		}
-
-		report, err := output.CreateMatchReport(matches, meanOfDeath)
+		dialect, err := parseDialect(dialectName)
 		if err != nil {
 			log.Fatal(err)
 			os.Exit(1)
 		}
 
-		j, err := json.MarshalIndent(report, "", "\t")
+		out := os.Stdout
+		if outputFile != "" && format != "json" {
+			out, err = os.Create(outputFile)
+			if err != nil {
+				log.Fatal(err)
+				os.Exit(1)
+			}
+			defer out.Close()
+		}
+
+		var checkpoints map[string]checkpoint.Checkpoint
+		if checkpointFile != "" {
+			if checkpoints, err = checkpoint.Load(checkpointFile); err != nil {
+				log.Fatal(err)
+				os.Exit(1)
+			}
+		}
+
+		options, err := reportOptions(ids, filter)
 		if err != nil {
 			log.Fatal(err)
 			os.Exit(1)
 		}
-		if outputFile != "" {
-			err := ioutil.WriteFile(outputFile, j, 0644)
+		report := quakelog.NewReport(options...)
+		// write writes each match as soon as it ends, unless the whole
+		// report is written at the end.
+		var write func(entry output.MatchEntry) error
+		switch format {
+		case "json":
+		case "ndjson":
+			encoder := json.NewEncoder(out)
+			write = func(entry output.MatchEntry) error { return encoder.Encode(entry) }
+		case "text":
+			write = func(entry output.MatchEntry) error { return output.WriteText(out, entry) }
+		default:
+			log.Fatal(fmt.Errorf("Unknown format %q", format))
+			os.Exit(1)
+		}
+		onMatch := func(match quakelog.Match) error {
+			if write == nil {
+				report.Add(match)
+				return nil
+			}
+			if entry, ok := report.Entry(match); ok {
+				return write(entry)
+			}
+			return nil
+		}
+		if workers == 0 {
+			workers = runtime.NumCPU()
+		}
+		var bar *progressBar
+		if !noProgress {
+			bar = newProgressBar(paths)
+		}
+
+		interrupted := false
+		for _, series := range input.Group(paths) {
+			source := series.Name
+			var cp *checkpoint.Checkpoint
+			key, resumable, err := checkpointKey(series)
+			if checkpoints != nil && resumable {
+				saved := checkpoints[key]
+				cp = &saved
+			}
+			switch {
+			case err != nil:
+			case cp == nil:
+				err = parseSeries(ctx, series, quakelog.Options{
+					Source:     source,
+					Dialect:    dialect,
+					Workers:    workers,
+					OnMatch:    onMatch,
+					OnProgress: bar.update,
+				}, bar.counter())
+				bar.next()
+			default:
+				ids := output.MatchIDs{}
+				err = readSeries(series, &parser.Stream{
+					Dialect: dialect,
+					OnMatchEnd: func(index int, match parser.Match) error {
+						return onMatch(quakelog.NewMatch(source, index, match, &ids))
+					},
+				}, cp)
+			}
+			if errors.Is(err, context.Canceled) {
+				interrupted = true
+				break
+			}
 			if err != nil {
+				bar.finish()
+				log.Fatal(fmt.Errorf("%s: %w", source, err))
+				os.Exit(1)
+			}
+			if cp != nil {
+				checkpoints[key] = *cp
+			}
+			// Logs resumed from a checkpoint are only interrupted between series.
+			if ctx.Err() != nil {
+				interrupted = true
+				break
+			}
+		}
+		bar.finish()
+		if interrupted {
+			log.Println("Interrupted")
+			if format == "json" && partial {
+				writeLayout(report, layout, outputFile)
+			}
+			os.Exit(130)
+		}
+		if checkpoints != nil {
+			if err := checkpoint.Save(checkpointFile, checkpoints); err != nil {
 				log.Fatal(err)
 				os.Exit(1)
 			}
+		}
+		if format != "json" {
 			os.Exit(0)
 		}
-		fmt.Println(string(j))
+
+		writeLayout(report, layout, outputFile)
 		os.Exit(0)
 	},
 }
 
+// parseWhere compiles the query of --where, or returns nil when it's empty.
+func parseWhere(expr string) (*query.Query, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return query.Parse(expr)
+}
+
+// writeLayout writes report in layout to the file in path, or to stdout
+// when path is empty.
+func writeLayout(report *quakelog.Report, layout string, path string) {
+	result, err := report.Layout(layout)
+	if err != nil {
+		log.Fatal(err)
+		os.Exit(1)
+	}
+	if err := writeReport(result, path); err != nil {
+		log.Fatal(err)
+		os.Exit(1)
+	}
+}
+
+// reportOptions returns the options of the report given by the flags
+// shared by vadrigar and report.
+func reportOptions(ids *identity.Identities, filter *query.Query) ([]quakelog.ReportOption, error) {
+	if layout == "map" && format == "json" && (timelineMode != "" || awardsFile != "" || len(scoring) > 0) {
+		return nil, errors.New("The map layout has no timelines, awards nor scores, use --layout list")
+	}
+	options := []quakelog.ReportOption{quakelog.WithIdentities(ids), quakelog.WithFilter(filter), quakelog.WithServer(serverName)}
+	if meanOfDeath {
+		options = append(options, quakelog.WithMeansOfDeath())
+	}
+	if excludeBots {
+		options = append(options, quakelog.WithoutBots())
+	}
+	switch timelineMode {
+	case "":
+	case "minute":
+		options = append(options, quakelog.WithTimeline(time.Minute))
+	case "event":
+		options = append(options, quakelog.WithTimeline(0))
+	default:
+		return nil, fmt.Errorf("Unknown timeline %q, expected minute or event", timelineMode)
+	}
+	if awardsFile != "" {
+		rules, err := awards.Load(awardsFile)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, quakelog.WithAwards(rules))
+	}
+	if len(scoring) > 0 {
+		rules, err := output.ParseScoring(scoring)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, quakelog.WithScoring(rules))
+	}
+	return options, nil
+}
+
+// writeText writes entries as text for people to read to the file in path,
+// or to stdout when path is empty.
+func writeText(entries []output.MatchEntry, path string) error {
+	out := os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	for _, entry := range entries {
+		if err := output.WriteText(out, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReport writes report as indented JSON to the file in path, or to
+// stdout when path is empty.
+func writeReport(report interface{}, path string) error {
+	j, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		return ioutil.WriteFile(path, j, 0644)
+	}
+	fmt.Println(string(j))
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(vadrigarCmd)
 
 	vadrigarCmd.Flags().BoolVarP(&meanOfDeath, "mean-of-death", "m", false, "Enable or disable logs of deaths by mean")
-	vadrigarCmd.Flags().StringVarP(&logFile, "log-file", "f", "", "Path for the Quake 3 Arena Server logs file")
+	vadrigarCmd.Flags().StringArrayVarP(&logFiles, "log-file", "f", []string{}, "Path, glob pattern or directory of Quake 3 Arena Server logs files, or - for stdin. Can be repeated")
+	vadrigarCmd.Flags().StringVar(&inputOrder, "order", input.ByName, `Order to read the log files: by "name", by "modtime", from the oldest modified, or by "time" of their first match`)
 	vadrigarCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "Output file. If not set, will print as JSON in stdout")
+	vadrigarCmd.Flags().StringVarP(&layout, "layout", "l", "map", `Layout of the report: "map" keyed by game_N or "list" of ordered matches`)
+	vadrigarCmd.Flags().StringVarP(&checkpointFile, "checkpoint", "c", "", "File to save where the log was read up to. If set, only the matches ended since the last run are reported")
+	vadrigarCmd.Flags().StringVarP(&where, "where", "w", "", `Report only the matches that satisfy a query, like "map = q3dm17 and kills > 50"`)
+	vadrigarCmd.Flags().StringVarP(&aliasesFile, "aliases", "a", "", "YAML, TOML or JSON file mapping the names, GUIDs and IPs of each player to a canonical name")
+	vadrigarCmd.Flags().BoolVar(&excludeBots, "exclude-bots", false, "Leave out the bots and the kills they made or suffered")
+	vadrigarCmd.Flags().StringVar(&timelineMode, "timeline", "", `Add the timeline of the scores to each match, sampled every "minute" or after every "event". Needs the list layout`)
+	vadrigarCmd.Flags().StringVar(&awardsFile, "awards", "", "YAML, TOML or JSON file with the rules of the awards won in each match. Needs the list layout")
+	vadrigarCmd.Flags().StringToIntVar(&scoring, "scoring", nil, `Add the scores of the players to each match, by the points of a "kill", a "suicide" and a death by the "world", like kill=1,world=-1. Needs the list layout`)
+	vadrigarCmd.Flags().StringVar(&serverName, "server-name", "", "Name of the server the logs are from, added to each match of the list layout")
+	vadrigarCmd.Flags().IntVarP(&workers, "workers", "j", 1, "How many matches to parse at the same time, 0 for one per CPU. Logs resumed from --checkpoint are parsed by one")
+	vadrigarCmd.Flags().StringVar(&dialectName, "dialect", "auto", `Log dialect: "auto", detected from each match, or one of baseq3, openarena, urt and quakelive`)
+	vadrigarCmd.Flags().BoolVar(&partial, "partial", false, "When interrupted by Ctrl-C, write the report of the matches that ended before it")
+	vadrigarCmd.Flags().BoolVar(&noProgress, "no-progress", false, "Don't show a progress bar, which is shown on terminals while large logs are parsed")
+	vadrigarCmd.Flags().StringVar(&format, "format", "json", `Output format: "json", "ndjson", which writes each match in a line as soon as it ends, or "text", which writes each match for people to read as soon as it ends`)
 	err := vadrigarCmd.MarkFlagRequired("log-file")
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,176 @@
+/*Package cmd handle all commands for a cli of quake-log.
+Copyright © 2020 Renato Biancalana da Silva <reesilva@pm.me>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/metrics"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/tail"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fromEnd      bool
+	pollInterval time.Duration
+	metricsAddr  string
+)
+
+// watchUpdate is a line written by the watch command.
+type watchUpdate struct {
+	Finished bool `json:"finished"`
+	output.MatchEntry
+}
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch follows a Quake 3 Arena Server log file and reports the match being played",
+	Long: `With watch command you will follow a Quake 3 Arena Server log file, like tail -F,
+even when it is rotated or truncated. The existing content is read first and then,
+for every change in the match being played, its report is written to stdout as a
+JSON line. The last line of each match has "finished" set to true. With
+--metrics-addr the activity of the server is also exported to Prometheus.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		dialect, err := parseDialect(dialectName)
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+		stats := metrics.New()
+		if metricsAddr != "" {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", stats.Handler())
+			go func() {
+				if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+					log.Fatal(err)
+					os.Exit(1)
+				}
+			}()
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		live := false
+		var last *output.MatchEntry
+		emit := func(index int, match parser.Match, finished bool) error {
+			entry := output.CreateMatchEntry(index, match, meanOfDeath)
+			if !finished && last != nil && reflect.DeepEqual(*last, entry) {
+				return nil
+			}
+			last = &entry
+			return encoder.Encode(watchUpdate{
+				Finished:   finished,
+				MatchEntry: entry,
+			})
+		}
+		emitCurrent := func(stream *parser.Stream) error {
+			if index, match, ok := stream.Current(); ok {
+				return emit(index, match, false)
+			}
+			return nil
+		}
+
+		stream := parser.Stream{
+			Dialect: dialect,
+			OnMatchEnd: func(index int, match parser.Match) error {
+				defer func() { last = nil }()
+				if !live {
+					return nil
+				}
+				return emit(index, match, true)
+			},
+			OnEvent: func(event parser.Event) error {
+				stats.Event(logFile, event)
+				return nil
+			},
+		}
+		opts := tail.Options{
+			PollInterval: pollInterval,
+			FromEnd:      fromEnd,
+			OnIdle: func() error {
+				if live {
+					return nil
+				}
+				live = true
+				return emitCurrent(&stream)
+			},
+			OnProgress: func(offset int64) {
+				stats.Progress(logFile, offset)
+			},
+		}
+		err = tail.Follow(ctx, logFile, opts, func(line string) error {
+			err := stream.ParseLine(line)
+			stats.Line(logFile, err)
+			if err != nil {
+				log.Println(err)
+				return nil
+			}
+			if !live {
+				return nil
+			}
+			return emitCurrent(&stream)
+		})
+		if err != nil {
+			log.Fatal(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// interruptContext returns a context that is done on Ctrl-C or SIGTERM.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().BoolVarP(&meanOfDeath, "mean-of-death", "m", false, "Enable or disable logs of deaths by mean")
+	watchCmd.Flags().StringVarP(&logFile, "log-file", "f", "", "Path for the Quake 3 Arena Server logs file")
+	watchCmd.Flags().BoolVar(&fromEnd, "from-end", false, "Skip the content already in the log file")
+	watchCmd.Flags().DurationVar(&pollInterval, "interval", tail.DefaultPollInterval, "How often to check the log file for new lines")
+	watchCmd.Flags().StringVar(&dialectName, "dialect", "auto", `Log dialect: "auto", detected from each match, or one of baseq3, openarena, urt and quakelive`)
+	watchCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Address to serve Prometheus metrics on /metrics, like :9100. Disabled if not set")
+	err := watchCmd.MarkFlagRequired("log-file")
+	if err != nil {
+		log.Fatal(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,378 @@
+// Package analyze flags the patterns of Quake 3 Arena matches that may
+// point to cheating or to a tampered log, for admins to review before
+// confirming their results.
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/quakelog"
+)
+
+const _worldID = 1022
+
+// The rules a Finding can be about.
+const (
+	// KillRate flags players that killed more per minute than is plausible.
+	KillRate = "kill_rate"
+	// RailgunRatio flags players that made almost every kill by railgun.
+	RailgunRatio = "railgun_ratio"
+	// SpawnKills flags players that killed the same victim many times
+	// right after the victim respawned.
+	SpawnKills = "spawn_kills"
+	// NoDeaths flags players that killed and never died over a long
+	// session.
+	NoDeaths = "no_deaths"
+	// NotConnected flags kills by client slots no player was connected to.
+	NotConnected = "not_connected"
+)
+
+// Severity is how likely a Finding is to need action.
+type Severity int
+
+// The severities, from the least to the most severe.
+const (
+	Info Severity = iota
+	Warning
+	Critical
+)
+
+var _severities = []string{"info", "warning", "critical"}
+
+// ParseSeverity returns the Severity called name.
+func ParseSeverity(name string) (Severity, error) {
+	for severity, severityName := range _severities {
+		if strings.EqualFold(name, severityName) {
+			return Severity(severity), nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown severity %q, expected one of %s", name, strings.Join(_severities, ", "))
+}
+
+func (s Severity) String() string {
+	if s < Info || s > Critical {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return _severities[s]
+}
+
+// MarshalText encodes s by its name, like "warning".
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes s from its name.
+func (s *Severity) UnmarshalText(text []byte) error {
+	severity, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// Finding is a suspicious pattern found in a match.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Player is who the finding is about, if anyone connected.
+	Player string `json:"player,omitempty"`
+	// Time is the server uptime, formatted as in the log, of the kill the
+	// finding is about, if it's about a single one.
+	Time    string `json:"time,omitempty"`
+	Message string `json:"message"`
+}
+
+// MatchFindings are the findings of a match, identified like an
+// output.MatchEntry.
+type MatchFindings struct {
+	Source    string    `json:"source,omitempty"`
+	ID        string    `json:"id"`
+	Index     int       `json:"index"`
+	Map       string    `json:"map"`
+	StartTime string    `json:"start_time"`
+	Findings  []Finding `json:"findings"`
+}
+
+// Thresholds are the limits above which the rules flag a player.
+type Thresholds struct {
+	// MaxKillsPerMinute is the highest kill rate that is not flagged. Twice
+	// as much is Critical.
+	MaxKillsPerMinute float64
+	// MaxRailgunRatio is the highest share of kills by railgun, from 0 to
+	// 1, that is not flagged.
+	MaxRailgunRatio float64
+	// MinKills is how many kills a player needs for their kill rate and
+	// railgun ratio to be judged.
+	MinKills int
+	// SpawnWindow is how long after dying a player is still at spawn.
+	SpawnWindow time.Duration
+	// MaxSpawnKills is how many times a player can kill the same victim at
+	// spawn without being flagged.
+	MaxSpawnKills int
+	// LongSession is how long a player must stay in a match, killing and
+	// never dying, to be flagged.
+	LongSession time.Duration
+}
+
+// DefaultThresholds are thresholds that fit free for all matches of
+// players of about the same skill.
+var DefaultThresholds = Thresholds{
+	MaxKillsPerMinute: 8,
+	MaxRailgunRatio:   0.9,
+	MinKills:          10,
+	SpawnWindow:       3 * time.Second,
+	MaxSpawnKills:     3,
+	LongSession:       10 * time.Minute,
+}
+
+// playerStats are the stats of a player, by name, in a match.
+type playerStats struct {
+	name    string
+	isBot   bool
+	session time.Duration
+	kills   int
+	deaths  int
+	railgun int
+}
+
+// Match returns the findings of match judged by t, sorted by rule and then
+// by player, or by time for the rules about single kills. The players are
+// judged by name, so the stats of a player who reconnects are summed.
+func Match(match quakelog.Match, t Thresholds) MatchFindings {
+	findings := MatchFindings{
+		Source:    match.Source,
+		ID:        match.ID,
+		Index:     match.Index,
+		Map:       match.MapName(),
+		StartTime: output.FormatTimestamp(match.StartTime),
+		Findings:  []Finding{},
+	}
+	stats := collectStats(match.Match)
+	for _, player := range stats {
+		if player.isBot || player.kills < t.MinKills || player.session <= 0 {
+			continue
+		}
+		rate := float64(player.kills) / player.session.Minutes()
+		if rate <= t.MaxKillsPerMinute {
+			continue
+		}
+		severity := Warning
+		if rate > 2*t.MaxKillsPerMinute {
+			severity = Critical
+		}
+		findings.Findings = append(findings.Findings, Finding{
+			Rule:     KillRate,
+			Severity: severity,
+			Player:   player.name,
+			Message:  fmt.Sprintf("%d kills in %s, %.1f per minute", player.kills, output.FormatTimestamp(player.session), rate),
+		})
+	}
+	for _, player := range stats {
+		if player.isBot || player.kills < t.MinKills {
+			continue
+		}
+		if ratio := float64(player.railgun) / float64(player.kills); ratio > t.MaxRailgunRatio {
+			findings.Findings = append(findings.Findings, Finding{
+				Rule:     RailgunRatio,
+				Severity: Warning,
+				Player:   player.name,
+				Message:  fmt.Sprintf("%d of %d kills by railgun, %.0f%%", player.railgun, player.kills, ratio*100),
+			})
+		}
+	}
+	findings.Findings = append(findings.Findings, spawnKills(match.Match, t)...)
+	for _, player := range stats {
+		if player.isBot || player.kills == 0 || player.deaths > 0 || player.session < t.LongSession {
+			continue
+		}
+		findings.Findings = append(findings.Findings, Finding{
+			Rule:     NoDeaths,
+			Severity: Info,
+			Player:   player.name,
+			Message:  fmt.Sprintf("%d kills and no deaths in %s", player.kills, output.FormatTimestamp(player.session)),
+		})
+	}
+	findings.Findings = append(findings.Findings, notConnected(match)...)
+	return findings
+}
+
+// collectStats returns the stats of the players of match sorted by name.
+func collectStats(match parser.Match) []*playerStats {
+	byName := map[string]*playerStats{}
+	stats := []*playerStats{}
+	statsOf := func(index int) *playerStats {
+		player := match.Players[index]
+		stat, ok := byName[player.Name]
+		if !ok {
+			stat = &playerStats{name: player.Name, isBot: player.IsBot}
+			byName[player.Name] = stat
+			stats = append(stats, stat)
+		}
+		return stat
+	}
+	end := endTime(match)
+	for index, player := range match.Players {
+		left := player.Disconnected
+		if left == 0 {
+			left = end
+		}
+		joined := player.Connected
+		if joined < match.StartTime {
+			joined = match.StartTime
+		}
+		if left > joined {
+			statsOf(index).session += left - joined
+		}
+	}
+	for _, kill := range match.Events {
+		victim := match.Victim(kill)
+		if victim != -1 {
+			statsOf(victim).deaths++
+		}
+		if kill.KillerID == _worldID || kill.KillerID == kill.VictimID {
+			continue
+		}
+		if killer := match.Killer(kill); killer != -1 {
+			stat := statsOf(killer)
+			stat.kills++
+			if strings.HasPrefix(output.Weapon(kill), "MOD_RAILGUN") {
+				stat.railgun++
+			}
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].name < stats[j].name
+	})
+	return stats
+}
+
+// spawnKills returns the findings of the players that killed the same
+// victim at spawn more than t.MaxSpawnKills times, sorted by killer and
+// victim, at the time of the first of those kills.
+func spawnKills(match parser.Match, t Thresholds) []Finding {
+	type pair struct{ killer, victim string }
+	died := map[string]time.Duration{}
+	count := map[pair]int{}
+	first := map[pair]time.Duration{}
+	for _, kill := range match.Events {
+		victimIndex := match.Victim(kill)
+		if victimIndex == -1 {
+			continue
+		}
+		victim := match.Players[victimIndex].Name
+		last, diedBefore := died[victim]
+		died[victim] = kill.Time
+		if !diedBefore || kill.KillerID == _worldID || kill.KillerID == kill.VictimID || kill.Time-last > t.SpawnWindow {
+			continue
+		}
+		killerIndex := match.Killer(kill)
+		if killerIndex == -1 || match.Players[killerIndex].IsBot {
+			continue
+		}
+		key := pair{match.Players[killerIndex].Name, victim}
+		if count[key] == 0 {
+			first[key] = kill.Time
+		}
+		count[key]++
+	}
+
+	pairs := []pair{}
+	for key, n := range count {
+		if n > t.MaxSpawnKills {
+			pairs = append(pairs, key)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].killer != pairs[j].killer {
+			return pairs[i].killer < pairs[j].killer
+		}
+		return pairs[i].victim < pairs[j].victim
+	})
+	findings := []Finding{}
+	for _, key := range pairs {
+		findings = append(findings, Finding{
+			Rule:     SpawnKills,
+			Severity: Warning,
+			Player:   key.killer,
+			Time:     output.FormatTimestamp(first[key]),
+			Message:  fmt.Sprintf("Killed %s %d times within %s of respawning", key.victim, count[key], t.SpawnWindow),
+		})
+	}
+	return findings
+}
+
+// notConnected returns the findings of the kills by players that had left
+// the match, and of the Kill lines skipped because no player had connected
+// to the slot of the killer, in the order they were logged.
+func notConnected(match quakelog.Match) []Finding {
+	findings := []Finding{}
+	for _, kill := range match.Events {
+		killer := match.Killer(kill)
+		if killer == -1 {
+			continue
+		}
+		player := match.Players[killer]
+		if player.Disconnected == 0 || player.Disconnected >= kill.Time {
+			continue
+		}
+		victim := "client " + strconv.Itoa(kill.VictimID)
+		if index := match.Victim(kill); index != -1 {
+			victim = match.Players[index].Name
+		}
+		findings = append(findings, Finding{
+			Rule:     NotConnected,
+			Severity: Critical,
+			Player:   player.Name,
+			Time:     output.FormatTimestamp(kill.Time),
+			Message:  fmt.Sprintf("Killed %s by %s after leaving at %s", victim, output.Weapon(kill), output.FormatTimestamp(player.Disconnected)),
+		})
+	}
+	for _, lineError := range match.Errors {
+		if !errors.Is(lineError, parser.ErrUnknownKiller) {
+			continue
+		}
+		finding := Finding{
+			Rule:     NotConnected,
+			Severity: Critical,
+			Message:  fmt.Sprintf("Line %d: kill by a client slot no player connected to", lineError.Line),
+		}
+		if value, _, _, ok := parser.Tokenize(lineError.Text); ok {
+			if timestamp, err := parser.ParseTimestamp(value); err == nil {
+				finding.Time = output.FormatTimestamp(timestamp)
+			}
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// endTime returns when match ended: the time of its ShutdownGame or, when
+// it ended without one, of the last event logged in it.
+func endTime(match parser.Match) time.Duration {
+	if match.EndTime != 0 {
+		return match.EndTime
+	}
+	end := match.StartTime
+	for _, player := range match.Players {
+		if player.Connected > end {
+			end = player.Connected
+		}
+		if player.Disconnected > end {
+			end = player.Disconnected
+		}
+	}
+	for _, kill := range match.Events {
+		if kill.Time > end {
+			end = kill.Time
+		}
+	}
+	return end
+}
@@ -0,0 +1,161 @@
+package analyze_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/analyze"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/quakelog"
+	"github.com/stretchr/testify/assert"
+)
+
+const _players = `  0:00 InitGame: \mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:01 ClientConnect: 3
+  0:01 ClientUserinfoChanged: 3 n\Mocinha\t\0
+  0:01 ClientConnect: 4
+  0:01 ClientUserinfoChanged: 4 n\Sarge\t\0
+  0:01 ClientUserinfo: 4 \name\Sarge\skill\3
+`
+
+// kills returns n lines of the same kill, every apart from start.
+func kills(start, every time.Duration, n int, kill string) string {
+	lines := ""
+	for i := 0; i < n; i++ {
+		lines += fmt.Sprintf("%6s Kill: %s\n", output.FormatTimestamp(start+time.Duration(i)*every), kill)
+	}
+	return lines
+}
+
+func parse(t *testing.T, log string) quakelog.Match {
+	matches, _, err := quakelog.Parse(strings.NewReader(_players+log), quakelog.Options{Source: "games.log", SkipErrors: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches[0]
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		thresholds analyze.Thresholds
+		findings   []analyze.Finding
+	}{
+		{
+			name: "Regular match",
+			log: kills(10*time.Second, 20*time.Second, 10, "2 3 6: Isgalamido killed Mocinha by MOD_ROCKET") +
+				kills(15*time.Second, 20*time.Second, 10, "3 2 1: Mocinha killed Isgalamido by MOD_SHOTGUN") +
+				" 15:00 ShutdownGame:\n",
+			thresholds: analyze.DefaultThresholds,
+			findings:   []analyze.Finding{},
+		},
+		{
+			name:       "Implausible kill rate",
+			log:        kills(10*time.Second, 5*time.Second, 12, "2 3 6: Isgalamido killed Mocinha by MOD_ROCKET") + "  1:01 ShutdownGame:\n",
+			thresholds: analyze.DefaultThresholds,
+			findings: []analyze.Finding{
+				{Rule: analyze.KillRate, Severity: analyze.Warning, Player: "Isgalamido", Message: "12 kills in 1:00, 12.0 per minute"},
+			},
+		},
+		{
+			name:       "Twice the kill rate",
+			log:        kills(10*time.Second, 5*time.Second, 12, "2 3 6: Isgalamido killed Mocinha by MOD_ROCKET") + "  1:01 ShutdownGame:\n",
+			thresholds: analyze.Thresholds{MaxKillsPerMinute: 5, MaxRailgunRatio: 1, MinKills: 10, LongSession: time.Hour},
+			findings: []analyze.Finding{
+				{Rule: analyze.KillRate, Severity: analyze.Critical, Player: "Isgalamido", Message: "12 kills in 1:00, 12.0 per minute"},
+			},
+		},
+		{
+			name: "Extreme railgun ratio",
+			log: kills(10*time.Second, 30*time.Second, 10, "2 3 10: Isgalamido killed Mocinha by MOD_RAILGUN") +
+				"  6:00 Kill: 3 2 6: Mocinha killed Isgalamido by MOD_ROCKET\n" +
+				"  8:00 ShutdownGame:\n",
+			thresholds: analyze.DefaultThresholds,
+			findings: []analyze.Finding{
+				{Rule: analyze.RailgunRatio, Severity: analyze.Warning, Player: "Isgalamido", Message: "10 of 10 kills by railgun, 100%"},
+			},
+		},
+		{
+			name: "Repeated kills of the same victim at spawn",
+			log: kills(10*time.Second, 2*time.Second, 5, "2 3 6: Isgalamido killed Mocinha by MOD_ROCKET") +
+				kills(20*time.Second, 2*time.Second, 5, "4 2 6: Sarge killed Isgalamido by MOD_ROCKET") +
+				"  1:00 ShutdownGame:\n",
+			thresholds: analyze.DefaultThresholds,
+			findings: []analyze.Finding{
+				{Rule: analyze.SpawnKills, Severity: analyze.Warning, Player: "Isgalamido", Time: "0:12", Message: "Killed Mocinha 4 times within 3s of respawning"},
+			},
+		},
+		{
+			name: "Long session without deaths",
+			log: "  1:00 Kill: 2 3 6: Isgalamido killed Mocinha by MOD_ROCKET\n" +
+				"  1:30 Kill: 1022 4 22: <world> killed Sarge by MOD_TRIGGER_HURT\n" +
+				"  2:00 ClientDisconnect: 4\n" +
+				" 11:00 Kill: 2 3 6: Isgalamido killed Mocinha by MOD_ROCKET\n" +
+				" 12:00 ShutdownGame:\n",
+			thresholds: analyze.DefaultThresholds,
+			findings: []analyze.Finding{
+				{Rule: analyze.NoDeaths, Severity: analyze.Info, Player: "Isgalamido", Message: "2 kills and no deaths in 11:59"},
+			},
+		},
+		{
+			name: "Kills by players not connected",
+			log: "  0:10 Kill: 2 3 6: Isgalamido killed Mocinha by MOD_ROCKET\n" +
+				"  0:20 ClientDisconnect: 2\n" +
+				"  0:30 Kill: 2 3 10: Isgalamido killed Mocinha by MOD_RAILGUN\n" +
+				"  0:40 Kill: 7 3 10: Nobody killed Mocinha by MOD_RAILGUN\n" +
+				"  1:00 ShutdownGame:\n",
+			thresholds: analyze.DefaultThresholds,
+			findings: []analyze.Finding{
+				{Rule: analyze.NotConnected, Severity: analyze.Critical, Player: "Isgalamido", Time: "0:30", Message: "Killed Mocinha by MOD_RAILGUN after leaving at 0:20"},
+				{Rule: analyze.NotConnected, Severity: analyze.Critical, Time: "0:40", Message: "Line 12: kill by a client slot no player connected to"},
+			},
+		},
+		{
+			name: "Players judged by the thresholds",
+			log: kills(10*time.Second, 2*time.Second, 5, "2 3 6: Isgalamido killed Mocinha by MOD_ROCKET") +
+				"  1:00 ShutdownGame:\n",
+			thresholds: analyze.Thresholds{MaxKillsPerMinute: 8, MaxRailgunRatio: 0.9, MinKills: 10, SpawnWindow: time.Second, MaxSpawnKills: 3, LongSession: 30 * time.Second},
+			findings: []analyze.Finding{
+				{Rule: analyze.NoDeaths, Severity: analyze.Info, Player: "Isgalamido", Message: "5 kills and no deaths in 0:59"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := parse(t, tt.log)
+			assert.Equal(t, analyze.MatchFindings{
+				Source:    "games.log",
+				ID:        match.ID,
+				Index:     1,
+				Map:       "q3dm17",
+				StartTime: "0:00",
+				Findings:  tt.findings,
+			}, analyze.Match(match, tt.thresholds))
+		})
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	severity, err := analyze.ParseSeverity("Warning")
+	assert.Nil(t, err)
+	assert.Equal(t, analyze.Warning, severity)
+
+	_, err = analyze.ParseSeverity("high")
+	assert.Equal(t, errors.New(`Unknown severity "high", expected one of info, warning, critical`), err)
+
+	j, err := json.Marshal(analyze.Finding{Rule: analyze.NotConnected, Severity: analyze.Critical, Message: "Line 1"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"rule":"not_connected","severity":"critical","message":"Line 1"}`, string(j))
+
+	var finding analyze.Finding
+	assert.Nil(t, json.Unmarshal(j, &finding))
+	assert.Equal(t, analyze.Critical, finding.Severity)
+}
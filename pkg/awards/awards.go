@@ -0,0 +1,246 @@
+// Package awards gives the achievements of each Quake 3 Arena match, like
+// "Most suicides" or "Untouchable", by rules over the stats of its players.
+package awards
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/spf13/viper"
+)
+
+// Ranks of a Rule: the award goes to the players with the most or the
+// fewest of its stat.
+const (
+	Most   = "most"
+	Fewest = "fewest"
+)
+
+// Rule is an award given in each match to the players with the most, or the
+// fewest, of a stat among the players that satisfy all its conditions, like
+// "deaths = 0" or "kills >= 5". The stats of a player in a match are:
+//
+//	kills           kills made, counted like the kills of the report
+//	deaths          times killed, by anyone or by the world
+//	suicides        times killed by themselves or by the world
+//	nemesis         most times the player killed the same other player
+//	kills_<weapon>  kills by a mean of death without the MOD_ prefix, like
+//	                kills_gauntlet or kills_ut_mod_lr300
+type Rule struct {
+	Name        string   `json:"name" mapstructure:"name"`
+	Description string   `json:"description,omitempty" mapstructure:"description"`
+	Stat        string   `json:"stat" mapstructure:"stat"`
+	Rank        string   `json:"rank,omitempty" mapstructure:"rank"`
+	Conditions  []string `json:"conditions,omitempty" mapstructure:"conditions"`
+}
+
+// Rules are compiled awards, ready to be given to the players of matches.
+type Rules struct {
+	rules []rule
+}
+
+type rule struct {
+	Rule
+	conditions []condition
+}
+
+type condition struct {
+	stat  string
+	op    string
+	value int
+}
+
+// New compiles rules, failing when one has no name, an unknown stat or rank
+// or a condition that is not a stat compared to a number.
+func New(rules []Rule) (*Rules, error) {
+	compiled := &Rules{rules: []rule{}}
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, errors.New("Award without a name")
+		}
+		r.Stat = strings.ToLower(r.Stat)
+		if !knownStat(r.Stat) {
+			return nil, fmt.Errorf("Unknown stat %q in award %q", r.Stat, r.Name)
+		}
+		switch r.Rank {
+		case "":
+			r.Rank = Most
+		case Most, Fewest:
+		default:
+			return nil, fmt.Errorf("Unknown rank %q in award %q, expected most or fewest", r.Rank, r.Name)
+		}
+		c := rule{Rule: r, conditions: []condition{}}
+		for _, expr := range r.Conditions {
+			cond, err := parseCondition(expr)
+			if err != nil {
+				return nil, fmt.Errorf("%w in award %q", err, r.Name)
+			}
+			c.conditions = append(c.conditions, cond)
+		}
+		compiled.rules = append(compiled.rules, c)
+	}
+	return compiled, nil
+}
+
+// Load reads the rules of the awards key of a YAML, TOML or JSON file,
+// like:
+//
+//	awards:
+//	  - name: Survivor
+//	    description: Fewest deaths
+//	    stat: deaths
+//	    rank: fewest
+//	  - name: Untouchable
+//	    stat: kills
+//	    conditions: [deaths = 0, kills >= 5]
+func Load(path string) (*Rules, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	rules := []Rule{}
+	if err := v.UnmarshalKey("awards", &rules); err != nil {
+		return nil, err
+	}
+	return New(rules)
+}
+
+// Match returns the awards won in match, in the order of the rules. An
+// award is given to every player tied with the most or the fewest of its
+// stat and it's left out when no player satisfies its conditions or, for
+// the most, when none has any of the stat.
+func (r *Rules) Match(match parser.Match) []output.Award {
+	players, stats := playerStats(match)
+	awards := []output.Award{}
+	for _, rule := range r.rules {
+		award := output.Award{Name: rule.Name, Description: rule.Description, Players: []string{}}
+		for _, player := range players {
+			if !rule.satisfied(stats[player]) {
+				continue
+			}
+			value := stats[player][rule.Stat]
+			better := value > award.Value
+			if rule.Rank == Fewest {
+				better = value < award.Value
+			}
+			switch {
+			case len(award.Players) == 0 || better:
+				award.Players = []string{player}
+				award.Value = value
+			case value == award.Value:
+				award.Players = append(award.Players, player)
+			}
+		}
+		if len(award.Players) > 0 && (rule.Rank == Fewest || award.Value > 0) {
+			awards = append(awards, award)
+		}
+	}
+	return awards
+}
+
+func (r rule) satisfied(stats map[string]int) bool {
+	for _, c := range r.conditions {
+		if !compare(stats[c.stat], c.op, c.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// playerStats returns the names of the players of match, in the order
+// they connected, and the stats of each one. A player who reconnects keeps
+// their stats.
+func playerStats(match parser.Match) ([]string, map[string]map[string]int) {
+	players := []string{}
+	stats := map[string]map[string]int{}
+	for _, player := range match.Players {
+		if stats[player.Name] == nil {
+			players = append(players, player.Name)
+			stats[player.Name] = map[string]int{}
+		}
+	}
+	victims := map[[2]string]int{}
+	for _, kill := range match.Events {
+		victim := ""
+		if index := match.Victim(kill); index != -1 {
+			victim = match.Players[index].Name
+			stats[victim]["deaths"]++
+		}
+		killer := ""
+		if index := match.Killer(kill); index != -1 {
+			killer = match.Players[index].Name
+			stats[killer]["kills"]++
+			stats[killer][weaponStat(output.Weapon(kill))]++
+		}
+		switch {
+		case victim == "":
+		case killer == "" || killer == victim:
+			stats[victim]["suicides"]++
+		default:
+			pair := [2]string{killer, victim}
+			victims[pair]++
+			if victims[pair] > stats[killer]["nemesis"] {
+				stats[killer]["nemesis"] = victims[pair]
+			}
+		}
+	}
+	return players, stats
+}
+
+// weaponStat returns the stat of the kills by weapon, like kills_railgun
+// for MOD_RAILGUN.
+func weaponStat(weapon string) string {
+	return "kills_" + strings.TrimPrefix(strings.ToLower(weapon), "mod_")
+}
+
+func knownStat(stat string) bool {
+	switch stat {
+	case "kills", "deaths", "suicides", "nemesis":
+		return true
+	}
+	return strings.HasPrefix(stat, "kills_") && len(stat) > len("kills_")
+}
+
+func parseCondition(expr string) (condition, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 3 {
+		return condition{}, fmt.Errorf("Invalid condition %q, expected a stat, an operator and a number, like \"deaths = 0\"", expr)
+	}
+	stat, op := strings.ToLower(fields[0]), fields[1]
+	if !knownStat(stat) {
+		return condition{}, fmt.Errorf("Unknown stat %q in condition %q", fields[0], expr)
+	}
+	switch op {
+	case "=", "!=", ">", ">=", "<", "<=":
+	default:
+		return condition{}, fmt.Errorf("Unknown operator %q in condition %q", op, expr)
+	}
+	value, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return condition{}, fmt.Errorf("Invalid number %q in condition %q", fields[2], expr)
+	}
+	return condition{stat: stat, op: op, value: value}, nil
+}
+
+func compare(a int, op string, b int) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
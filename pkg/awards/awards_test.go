@@ -0,0 +1,149 @@
+package awards_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/reesilva/quake-log/pkg/awards"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var _match = parser.Match{
+	Players: []parser.Player{
+		{ID: 2, Name: "Isgalamido"},
+		{ID: 3, Name: "Mocinha"},
+		{ID: 4, Name: "Sarge"},
+		{ID: 5, Name: "Dono da Bola"},
+	},
+	Events: []parser.Kill{
+		{KillerID: 2, VictimID: 3, MeanOfDeath: 2},
+		{KillerID: 2, VictimID: 3, MeanOfDeath: 2},
+		{KillerID: 2, VictimID: 4, MeanOfDeath: 10},
+		{KillerID: 3, VictimID: 4, MeanOfDeath: 6},
+		{KillerID: 1022, VictimID: 3, MeanOfDeath: 22},
+		{KillerID: 3, VictimID: 3, MeanOfDeath: 7},
+		{KillerID: 4, VictimID: 5, MeanOfDeath: 10},
+	},
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []awards.Rule
+		awards []output.Award
+	}{
+		{
+			name:   "Most of the stat",
+			rules:  []awards.Rule{{Name: "Most suicides", Stat: "suicides"}},
+			awards: []output.Award{{Name: "Most suicides", Players: []string{"Mocinha"}, Value: 2}},
+		},
+		{
+			name:   "Fewest of the stat",
+			rules:  []awards.Rule{{Name: "Survivor", Description: "Fewest deaths", Stat: "deaths", Rank: awards.Fewest}},
+			awards: []output.Award{{Name: "Survivor", Description: "Fewest deaths", Players: []string{"Isgalamido"}, Value: 0}},
+		},
+		{
+			name: "Kills by weapon and of the same victim",
+			rules: []awards.Rule{
+				{Name: "Gauntlet master", Stat: "kills_gauntlet", Conditions: []string{"kills_gauntlet > 0"}},
+				{Name: "Nemesis", Stat: "Nemesis"},
+			},
+			awards: []output.Award{
+				{Name: "Gauntlet master", Players: []string{"Isgalamido"}, Value: 2},
+				{Name: "Nemesis", Players: []string{"Isgalamido"}, Value: 2},
+			},
+		},
+		{
+			name:   "Most of a stat no player has",
+			rules:  []awards.Rule{{Name: "BFG master", Stat: "kills_bfg"}},
+			awards: []output.Award{},
+		},
+		{
+			name:   "Tied players",
+			rules:  []awards.Rule{{Name: "Railgunner", Stat: "kills_railgun"}},
+			awards: []output.Award{{Name: "Railgunner", Players: []string{"Isgalamido", "Sarge"}, Value: 1}},
+		},
+		{
+			name: "Players that satisfy the conditions",
+			rules: []awards.Rule{
+				{Name: "Untouchable", Stat: "kills", Conditions: []string{"deaths = 0", "kills >= 3"}},
+				{Name: "Pacifist", Stat: "deaths", Rank: awards.Fewest, Conditions: []string{"kills = 0", "deaths != 1"}},
+			},
+			awards: []output.Award{{Name: "Untouchable", Players: []string{"Isgalamido"}, Value: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := awards.New(tt.rules)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.awards, rules.Match(_match))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		rule awards.Rule
+		err  error
+	}{
+		{
+			name: "Without a name",
+			rule: awards.Rule{Stat: "kills"},
+			err:  errors.New("Award without a name"),
+		},
+		{
+			name: "Unknown stat",
+			rule: awards.Rule{Name: "Camper", Stat: "distance"},
+			err:  errors.New(`Unknown stat "distance" in award "Camper"`),
+		},
+		{
+			name: "Unknown rank",
+			rule: awards.Rule{Name: "Survivor", Stat: "deaths", Rank: "least"},
+			err:  errors.New(`Unknown rank "least" in award "Survivor", expected most or fewest`),
+		},
+		{
+			name: "Condition that is not a comparison",
+			rule: awards.Rule{Name: "Untouchable", Stat: "kills", Conditions: []string{"deaths == 0"}},
+			err:  errors.New(`Unknown operator "==" in condition "deaths == 0" in award "Untouchable"`),
+		},
+		{
+			name: "Condition that is not a number",
+			rule: awards.Rule{Name: "Untouchable", Stat: "kills", Conditions: []string{"deaths = none"}},
+			err:  errors.New(`Invalid number "none" in condition "deaths = none" in award "Untouchable"`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := awards.New([]awards.Rule{tt.rule})
+			assert.EqualError(t, err, tt.err.Error())
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "awards.yaml")
+	content := "awards:\n  - name: Survivor\n    stat: deaths\n    rank: fewest\n    conditions: [kills > 0]\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := awards.Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []output.Award{{Name: "Survivor", Players: []string{"Isgalamido"}, Value: 0}}, rules.Match(_match))
+
+	_, err = awards.Load(filepath.Join(dir, "missing.yaml"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,231 @@
+// Package checkpoint reads Quake 3 Arena Server log files incrementally,
+// resuming each run from where the previous one stopped.
+package checkpoint
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+)
+
+// _fingerprintSize is how many bytes before the offset are hashed to
+// tell whether the file is still the one the checkpoint was taken from.
+const _fingerprintSize int64 = 4096
+
+// Checkpoint is how far a log file was read and the state of the parser
+// at that point.
+type Checkpoint struct {
+	// Offset is where the next line to be read starts.
+	Offset int64 `json:"offset"`
+	// Fingerprint identifies the content of the file up to Offset.
+	Fingerprint string `json:"fingerprint"`
+	// Device and Inode identify the file itself, so it's found again after
+	// being rotated, and Size is how big it was. Device and Inode are zero
+	// on systems without them, like Windows.
+	Device uint64             `json:"device,omitempty"`
+	Inode  uint64             `json:"inode,omitempty"`
+	Size   int64              `json:"size,omitempty"`
+	State  parser.StreamState `json:"state"`
+}
+
+// Read parses every complete line of the log file in path through
+// stream, starting from cp when the file is still the one it was taken
+// from, and returns the Checkpoint to resume from on the next run. The
+// match being played is kept in the Checkpoint instead of being closed.
+//
+// If the file was rotated or truncated since cp, the lines written to the
+// file cp was taken from after it are read first, when that file is still
+// next to path, like games.log.1, and uncompressed. The file in path is
+// then read from the start, keeping the state of the parser, since a new
+// log file continues the match that was being played. A zero Checkpoint
+// reads the whole file.
+func Read(path string, cp Checkpoint, stream *parser.Stream) (Checkpoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return cp, err
+	}
+	defer file.Close()
+
+	stream.Restore(cp.State)
+	offset := int64(0)
+	if cp.Offset > 0 {
+		same, err := cp.identifies(file, true)
+		if err != nil {
+			return cp, err
+		}
+		if same {
+			offset = cp.Offset
+		} else if err := readRotated(path, cp, stream); err != nil {
+			return cp, err
+		}
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return cp, err
+	}
+	read, err := readLines(file, stream, false)
+	if err != nil {
+		return cp, err
+	}
+	offset += read
+
+	info, err := file.Stat()
+	if err != nil {
+		return cp, err
+	}
+	next := Checkpoint{
+		Offset: offset,
+		Size:   info.Size(),
+		State:  stream.State(),
+	}
+	next.Device, next.Inode, _ = fileID(info)
+	if next.Fingerprint, err = fingerprint(file, offset); err != nil {
+		return cp, err
+	}
+	return next, nil
+}
+
+// identifies reports whether file has the same content up to cp.Offset as
+// the file cp was taken from and, if sameFile is set, whether it's that
+// file, with the same identity, when both have one, and not truncated.
+func (cp Checkpoint) identifies(file *os.File, sameFile bool) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < cp.Offset || (sameFile && info.Size() < cp.Size) {
+		return false, nil
+	}
+	if device, inode, ok := fileID(info); sameFile && ok && cp.Inode != 0 && (device != cp.Device || inode != cp.Inode) {
+		return false, nil
+	}
+	fingerprint, err := fingerprint(file, cp.Offset)
+	return fingerprint == cp.Fingerprint, err
+}
+
+// readRotated parses through stream the lines written after cp to the file
+// it was taken from, which was rotated next to path. It's the file with the
+// identity of cp or, when logs are rotated by copying and truncating them,
+// the first one by name with its content. Nothing is read when there's
+// none, like when the rotated file was compressed or removed.
+func readRotated(path string, cp Checkpoint, stream *parser.Stream) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, sameFile := range []bool{true, false} {
+		for _, info := range infos {
+			name := info.Name()
+			if !info.Mode().IsRegular() || name == base || !strings.HasPrefix(name, base) {
+				continue
+			}
+			if device, inode, ok := fileID(info); sameFile && (!ok || device != cp.Device || inode != cp.Inode) {
+				continue
+			}
+			file, err := os.Open(filepath.Join(dir, name))
+			if err != nil {
+				return err
+			}
+			same, err := cp.identifies(file, sameFile)
+			if err == nil && same {
+				_, err = file.Seek(cp.Offset, io.SeekStart)
+				if err == nil {
+					_, err = readLines(file, stream, true)
+				}
+				file.Close()
+				return err
+			}
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readLines parses every complete line of file, from where it's at,
+// through stream and returns how many bytes were read. The last line is
+// only parsed without its new line when final is set, since otherwise it's
+// still being written.
+func readLines(file *os.File, stream *parser.Stream, final bool) (int64, error) {
+	read := int64(0)
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF && (!final || line == "") {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return read, err
+		}
+		read += int64(len(line))
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if err := stream.ParseLine(line); err != nil {
+			return read, err
+		}
+	}
+	return read, nil
+}
+
+// fingerprint hashes the bytes right before offset. It returns an empty
+// string when the file is smaller than offset.
+func fingerprint(file *os.File, offset int64) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Size() < offset {
+		return "", nil
+	}
+	start := offset - _fingerprintSize
+	if start < 0 {
+		start = 0
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(file, start, offset-start)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Load reads the checkpoints saved by Save in path, keyed by the log file
+// they belong to. A missing file has no checkpoints.
+func Load(path string) (map[string]Checkpoint, error) {
+	checkpoints := map[string]Checkpoint{}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &checkpoints); err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
+// Save writes checkpoints to path as JSON, replacing it atomically.
+func Save(path string, checkpoints map[string]Checkpoint) error {
+	content, err := json.Marshal(checkpoints)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,163 @@
+package checkpoint_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/checkpoint"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func write(t *testing.T, path string, content string, flag int) {
+	file, err := os.OpenFile(path, flag|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, path string, cp checkpoint.Checkpoint) (checkpoint.Checkpoint, map[int][]parser.Player) {
+	got := map[int][]parser.Player{}
+	stream := parser.Stream{
+		OnMatchEnd: func(index int, match parser.Match) error {
+			got[index] = match.Players
+			return nil
+		},
+	}
+	next, err := checkpoint.Read(path, cp, &stream)
+	assert.NoError(t, err)
+	return next, got
+}
+
+func TestRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "games.log")
+
+	write(t, path, "  0:00 InitGame: \\mapname\\q3dm17\n 0:01 ClientConnect: 2\n 0:02 ShutdownGame:\n 0:03 InitGame: \\mapname\\q3dm6\n 0:04 ClientConn", os.O_TRUNC)
+	cp, got := read(t, path, checkpoint.Checkpoint{})
+	assert.Equal(t, map[int][]parser.Player{1: {{ID: 2, Connected: time.Second}}}, got)
+	assert.Equal(t, 2, cp.State.Count)
+	assert.NotNil(t, cp.State.Match)
+	assert.Equal(t, int64(len("  0:00 InitGame: \\mapname\\q3dm17\n 0:01 ClientConnect: 2\n 0:02 ShutdownGame:\n 0:03 InitGame: \\mapname\\q3dm6\n")), cp.Offset)
+
+	t.Run("Resumes from the checkpoint", func(t *testing.T) {
+		write(t, path, "ect: 3\n 0:05 ShutdownGame:\n", os.O_APPEND)
+		next, got := read(t, path, cp)
+		assert.Equal(t, map[int][]parser.Player{2: {{ID: 3, Connected: 4 * time.Second}}}, got)
+		assert.Nil(t, next.State.Match)
+
+		again, got := read(t, path, next)
+		assert.Equal(t, map[int][]parser.Player{}, got)
+		assert.Equal(t, next, again)
+	})
+
+	t.Run("Reads a rotated file from the start keeping the match", func(t *testing.T) {
+		write(t, path, " 0:04 ClientConnect: 4\n 0:05 ShutdownGame:\n", os.O_TRUNC)
+		next, got := read(t, path, cp)
+		assert.Equal(t, map[int][]parser.Player{2: {{ID: 4, Connected: 4 * time.Second}}}, got)
+		assert.Equal(t, 2, next.State.Count)
+	})
+
+	t.Run("Reads a file with different content from the start", func(t *testing.T) {
+		write(t, path, "  0:00 InitGame: \\mapname\\q3dm7\n 0:01 ClientConnect: 5\n 0:02 ShutdownGame:\n 0:03 InitGame: \\mapname\\q3dm7\n 0:04 ClientConnect: 6\n 0:05 ShutdownGame:\n", os.O_TRUNC)
+		_, got := read(t, path, cp)
+		assert.Equal(t, map[int][]parser.Player{2: {}, 3: {{ID: 5, Connected: time.Second}}, 4: {{ID: 6, Connected: 4 * time.Second}}}, got)
+	})
+}
+
+func TestReadRotated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "games.log")
+	rotated := filepath.Join(dir, "games.log.1")
+
+	start := "  0:00 InitGame: \\mapname\\q3dm17\n 0:01 ClientConnect: 2\n"
+	tests := []struct {
+		name   string
+		rotate func(t *testing.T)
+	}{
+		{
+			name: "Rotated by renaming",
+			rotate: func(t *testing.T) {
+				if err := os.Rename(path, rotated); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "Rotated by copying and truncating",
+			rotate: func(t *testing.T) {
+				content, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				write(t, rotated, string(content), os.O_TRUNC)
+				write(t, path, "", os.O_TRUNC)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(rotated)
+			write(t, path, start, os.O_TRUNC)
+			cp, got := read(t, path, checkpoint.Checkpoint{})
+			assert.Equal(t, map[int][]parser.Player{}, got)
+
+			write(t, path, " 0:02 ClientConnect: 3\n 0:03 ClientConnect: 4", os.O_APPEND)
+			tt.rotate(t)
+			write(t, path, " 0:04 ClientConnect: 5\n 0:05 ShutdownGame:\n", os.O_APPEND)
+			next, got := read(t, path, cp)
+			assert.Equal(t, map[int][]parser.Player{1: {
+				{ID: 2, Connected: time.Second},
+				{ID: 3, Connected: 2 * time.Second},
+				{ID: 4, Connected: 3 * time.Second},
+				{ID: 5, Connected: 4 * time.Second},
+			}}, got)
+			assert.Equal(t, int64(len(" 0:04 ClientConnect: 5\n 0:05 ShutdownGame:\n")), next.Offset)
+		})
+	}
+}
+
+func TestLoadAndSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoints.json")
+
+	checkpoints, err := checkpoint.Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]checkpoint.Checkpoint{}, checkpoints)
+
+	checkpoints["games.log"] = checkpoint.Checkpoint{
+		Offset:      42,
+		Fingerprint: "abc",
+		State: parser.StreamState{
+			Count: 3,
+			Match: &parser.Match{
+				Players:  []parser.Player{{ID: 2, Name: "Isgalamido"}},
+				Events:   []parser.Kill{{KillerID: 1022, VictimID: 2, MeanOfDeath: 22}},
+				Settings: map[string]string{"mapname": "q3dm17"},
+			},
+		},
+	}
+	assert.NoError(t, checkpoint.Save(path, checkpoints))
+	loaded, err := checkpoint.Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, checkpoints, loaded)
+}
@@ -0,0 +1,264 @@
+// Package diff compares two reports of the same Quake 3 Arena matches, like
+// the reports of a log parsed by two versions of the parser or of a log and
+// its re-export.
+package diff
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/reesilva/quake-log/pkg/output"
+)
+
+// Diff is what changed from an old report to a new one.
+type Diff struct {
+	// Added are the matches only in the new report and Removed the ones
+	// only in the old report.
+	Added   []output.MatchEntry `json:"added"`
+	Removed []output.MatchEntry `json:"removed"`
+	// Changed are the matches in both reports with different kills.
+	Changed []MatchDiff `json:"changed"`
+	// Unchanged is how many matches are in both reports with the same kills.
+	Unchanged int `json:"unchanged"`
+}
+
+// Ref identifies a match in one of the reports.
+type Ref struct {
+	Source string `json:"source,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Index  int    `json:"index"`
+}
+
+// MatchDiff is what changed in a match in both reports. Only the counts
+// that changed are listed, and the ranking only when it changed.
+type MatchDiff struct {
+	Map          string            `json:"map,omitempty"`
+	StartTime    string            `json:"start_time,omitempty"`
+	Old          Ref               `json:"old"`
+	New          Ref               `json:"new"`
+	TotalKills   *Change           `json:"total_kills,omitempty"`
+	Kills        map[string]Change `json:"kills,omitempty"`
+	KillsByMeans map[string]Change `json:"kills_by_means,omitempty"`
+	Ranking      *RankingChange    `json:"ranking,omitempty"`
+}
+
+// Change is a count in the old and in the new report.
+type Change struct {
+	Old int `json:"old"`
+	New int `json:"new"`
+}
+
+// RankingChange are the players of a match from the most to the fewest
+// kills in the old and in the new report.
+type RankingChange struct {
+	Old []string `json:"old"`
+	New []string `json:"new"`
+}
+
+// Reports compares the matches of the old report to the ones of the new
+// report. Matches are paired by ID and then, since a match parsed
+// differently has another ID, by map and start time, in the order they were
+// played. When one of the reports is in the map layout, which has neither,
+// they're paired by index instead.
+func Reports(oldEntries, newEntries []output.MatchEntry) Diff {
+	d := Diff{
+		Added:   []output.MatchEntry{},
+		Removed: []output.MatchEntry{},
+		Changed: []MatchDiff{},
+	}
+	pairs := make([]int, len(newEntries))
+	for i := range pairs {
+		pairs[i] = -1
+	}
+	paired := make([]bool, len(oldEntries))
+	keys := []func(entry output.MatchEntry) string{matchID, playedAt}
+	if !played(oldEntries) || !played(newEntries) {
+		keys[1] = position
+	}
+	for _, key := range keys {
+		unpaired := map[string][]int{}
+		for i, entry := range oldEntries {
+			if k := key(entry); !paired[i] && k != "" {
+				unpaired[k] = append(unpaired[k], i)
+			}
+		}
+		for i, entry := range newEntries {
+			k := key(entry)
+			if pairs[i] != -1 || k == "" || len(unpaired[k]) == 0 {
+				continue
+			}
+			pairs[i] = unpaired[k][0]
+			paired[pairs[i]] = true
+			unpaired[k] = unpaired[k][1:]
+		}
+	}
+
+	for i, entry := range oldEntries {
+		if !paired[i] {
+			d.Removed = append(d.Removed, entry)
+		}
+	}
+	for i, entry := range newEntries {
+		if pairs[i] == -1 {
+			d.Added = append(d.Added, entry)
+			continue
+		}
+		old := oldEntries[pairs[i]]
+		if old.MatchReport.Equal(entry.MatchReport) {
+			d.Unchanged++
+			continue
+		}
+		d.Changed = append(d.Changed, compare(old, entry))
+	}
+	return d
+}
+
+// ReadReport reads a JSON report written by vadrigar or report, in the list
+// or in the map layout. The matches of the map layout only have their
+// reports and their index, taken from their game_N key.
+func ReadReport(r io.Reader) ([]output.MatchEntry, error) {
+	reader := bufio.NewReader(r)
+	first, err := firstByte(reader)
+	if err != nil {
+		return nil, err
+	}
+	entries := []output.MatchEntry{}
+	if first == '[' {
+		if err := json.NewDecoder(reader).Decode(&entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	}
+
+	games := map[string]output.MatchReport{}
+	if err := json.NewDecoder(reader).Decode(&games); err != nil {
+		return nil, err
+	}
+	for key, report := range games {
+		index, err := strconv.Atoi(strings.TrimPrefix(key, "game_"))
+		if err != nil || !strings.HasPrefix(key, "game_") {
+			return nil, fmt.Errorf("Unknown game %q, expected game_N", key)
+		}
+		entries = append(entries, output.MatchEntry{Index: index, MatchReport: report})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Index < entries[j].Index })
+	return entries, nil
+}
+
+// IsReport reports whether r starts like a JSON report instead of a log.
+func IsReport(r *bufio.Reader) (bool, error) {
+	first, err := firstByte(r)
+	if err == io.EOF {
+		return false, nil
+	}
+	return first == '[' || first == '{', err
+}
+
+// firstByte returns the first byte of r that is not a space, without
+// reading it.
+func firstByte(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			return b, r.UnreadByte()
+		}
+	}
+}
+
+func matchID(entry output.MatchEntry) string {
+	return entry.ID
+}
+
+func playedAt(entry output.MatchEntry) string {
+	return entry.Map + "\x00" + entry.StartTime
+}
+
+func position(entry output.MatchEntry) string {
+	return strconv.Itoa(entry.Index)
+}
+
+// played reports whether entries have the maps and start times of their
+// matches, which the map layout doesn't.
+func played(entries []output.MatchEntry) bool {
+	for _, entry := range entries {
+		if entry.Map != "" || entry.StartTime != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(old, next output.MatchEntry) MatchDiff {
+	d := MatchDiff{
+		Map:       next.Map,
+		StartTime: next.StartTime,
+		Old:       Ref{Source: old.Source, ID: old.ID, Index: old.Index},
+		New:       Ref{Source: next.Source, ID: next.ID, Index: next.Index},
+	}
+	if old.TotalKills != next.TotalKills {
+		d.TotalKills = &Change{Old: old.TotalKills, New: next.TotalKills}
+	}
+	d.Kills = changes(old.Kills, next.Kills)
+	d.KillsByMeans = changes(old.KillsByMeans, next.KillsByMeans)
+	oldRanking, newRanking := ranking(old.MatchReport), ranking(next.MatchReport)
+	if !equalNames(oldRanking, newRanking) {
+		d.Ranking = &RankingChange{Old: oldRanking, New: newRanking}
+	}
+	return d
+}
+
+// changes returns the counts that are different in old and next, or nil
+// when none is.
+func changes(old, next map[string]int) map[string]Change {
+	var changed map[string]Change
+	add := func(key string) {
+		if old[key] == next[key] {
+			return
+		}
+		if changed == nil {
+			changed = map[string]Change{}
+		}
+		changed[key] = Change{Old: old[key], New: next[key]}
+	}
+	for key := range old {
+		add(key)
+	}
+	for key := range next {
+		add(key)
+	}
+	return changed
+}
+
+// ranking returns the players of report from the most to the fewest kills,
+// tied players by name.
+func ranking(report output.MatchReport) []string {
+	players := append([]string{}, report.Players...)
+	sort.SliceStable(players, func(i, j int) bool {
+		ki, kj := report.Kills[players[i]], report.Kills[players[j]]
+		if ki != kj {
+			return ki > kj
+		}
+		return players[i] < players[j]
+	})
+	return players
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+package diff_test
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/reesilva/quake-log/pkg/diff"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func entry(id string, index int, start string, kills map[string]int) output.MatchEntry {
+	total := 0
+	for _, count := range kills {
+		total += count
+	}
+	return output.MatchEntry{
+		Source:    "games.log",
+		ID:        id,
+		Index:     index,
+		Map:       "q3dm17",
+		StartTime: start,
+		MatchReport: output.MatchReport{
+			TotalKills: total,
+			Players:    []string{"Isgalamido", "Mocinha"},
+			Kills:      kills,
+		},
+	}
+}
+
+func TestReports(t *testing.T) {
+	first := entry("a1", 1, "0:00", map[string]int{"Isgalamido": 2})
+	second := entry("b2", 2, "20:37", map[string]int{"Isgalamido": 1, "Mocinha": 2})
+	third := entry("c3", 3, "25:00", map[string]int{})
+	changed := entry("d4", 2, "20:37", map[string]int{"Isgalamido": 3, "Mocinha": 2})
+	reordered := entry("a1", 1, "0:00", map[string]int{"Isgalamido": 2, "Mocinha": 0})
+	reordered.Players = []string{"Mocinha", "Isgalamido"}
+
+	tests := []struct {
+		name string
+		old  []output.MatchEntry
+		new  []output.MatchEntry
+		want diff.Diff
+	}{
+		{
+			name: "Same report",
+			old:  []output.MatchEntry{first, second},
+			new:  []output.MatchEntry{first, second},
+			want: diff.Diff{Added: []output.MatchEntry{}, Removed: []output.MatchEntry{}, Changed: []diff.MatchDiff{}, Unchanged: 2},
+		},
+		{
+			name: "Same match with players in another order and kills of 0",
+			old:  []output.MatchEntry{first},
+			new:  []output.MatchEntry{reordered},
+			want: diff.Diff{Added: []output.MatchEntry{}, Removed: []output.MatchEntry{}, Changed: []diff.MatchDiff{}, Unchanged: 1},
+		},
+		{
+			name: "Matches added and removed",
+			old:  []output.MatchEntry{first, second},
+			new:  []output.MatchEntry{second, third},
+			want: diff.Diff{
+				Added:     []output.MatchEntry{third},
+				Removed:   []output.MatchEntry{first},
+				Changed:   []diff.MatchDiff{},
+				Unchanged: 1,
+			},
+		},
+		{
+			name: "Matches parsed differently paired by map and start time",
+			old:  []output.MatchEntry{first, second},
+			new:  []output.MatchEntry{first, changed},
+			want: diff.Diff{
+				Added:   []output.MatchEntry{},
+				Removed: []output.MatchEntry{},
+				Changed: []diff.MatchDiff{{
+					Map:        "q3dm17",
+					StartTime:  "20:37",
+					Old:        diff.Ref{Source: "games.log", ID: "b2", Index: 2},
+					New:        diff.Ref{Source: "games.log", ID: "d4", Index: 2},
+					TotalKills: &diff.Change{Old: 3, New: 5},
+					Kills:      map[string]diff.Change{"Isgalamido": {Old: 1, New: 3}},
+					Ranking: &diff.RankingChange{
+						Old: []string{"Mocinha", "Isgalamido"},
+						New: []string{"Isgalamido", "Mocinha"},
+					},
+				}},
+				Unchanged: 1,
+			},
+		},
+		{
+			name: "Matches paired by index with the map layout",
+			old: []output.MatchEntry{
+				{Index: 1, MatchReport: first.MatchReport},
+				{Index: 2, MatchReport: second.MatchReport},
+			},
+			new: []output.MatchEntry{first, changed},
+			want: diff.Diff{
+				Added:   []output.MatchEntry{},
+				Removed: []output.MatchEntry{},
+				Changed: []diff.MatchDiff{{
+					Map:        "q3dm17",
+					StartTime:  "20:37",
+					Old:        diff.Ref{Index: 2},
+					New:        diff.Ref{Source: "games.log", ID: "d4", Index: 2},
+					TotalKills: &diff.Change{Old: 3, New: 5},
+					Kills:      map[string]diff.Change{"Isgalamido": {Old: 1, New: 3}},
+					Ranking: &diff.RankingChange{
+						Old: []string{"Mocinha", "Isgalamido"},
+						New: []string{"Isgalamido", "Mocinha"},
+					},
+				}},
+				Unchanged: 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, diff.Reports(tt.old, tt.new))
+		})
+	}
+}
+
+func TestReadReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  string
+		entries []output.MatchEntry
+		err     error
+	}{
+		{
+			name:   "List layout",
+			report: `[{"id": "a1", "index": 1, "map": "q3dm17", "start_time": "0:00", "total_kills": 1, "players": ["Isgalamido"], "kills": {"Isgalamido": 1}}]`,
+			entries: []output.MatchEntry{{
+				ID: "a1", Index: 1, Map: "q3dm17", StartTime: "0:00",
+				MatchReport: output.MatchReport{TotalKills: 1, Players: []string{"Isgalamido"}, Kills: map[string]int{"Isgalamido": 1}},
+			}},
+		},
+		{
+			name:   "Map layout in the order of the games",
+			report: "\n{\"game_10\": {\"total_kills\": 1}, \"game_2\": {\"total_kills\": 0}}",
+			entries: []output.MatchEntry{
+				{Index: 2, MatchReport: output.MatchReport{}},
+				{Index: 10, MatchReport: output.MatchReport{TotalKills: 1}},
+			},
+		},
+		{
+			name:   "Games not keyed by game_N",
+			report: `{"match_1": {"total_kills": 1}}`,
+			err:    errors.New(`Unknown game "match_1", expected game_N`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := diff.ReadReport(strings.NewReader(tt.report))
+			assert.Equal(t, tt.err, err)
+			if tt.err == nil {
+				assert.Equal(t, tt.entries, entries)
+			}
+		})
+	}
+}
+
+func TestIsReport(t *testing.T) {
+	for input, want := range map[string]bool{
+		"  [\n":                         true,
+		"{}":                            true,
+		"  0:00 InitGame: \\mapname\\x": false,
+		"":                              false,
+	} {
+		isReport, err := diff.IsReport(bufio.NewReader(strings.NewReader(input)))
+		assert.Nil(t, err)
+		assert.Equal(t, want, isReport, input)
+	}
+}
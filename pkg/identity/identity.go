@@ -0,0 +1,254 @@
+// Package identity resolves the many names a Quake 3 Arena player uses to a
+// single canonical one.
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/spf13/viper"
+)
+
+// Identity is a player, by canonical name, and everything that identifies
+// them in the logs.
+type Identity struct {
+	Name    string   `json:"name" mapstructure:"name"`
+	Aliases []string `json:"aliases,omitempty" mapstructure:"aliases"`
+	GUIDs   []string `json:"guids,omitempty" mapstructure:"guids"`
+	IPs     []string `json:"ips,omitempty" mapstructure:"ips"`
+}
+
+// Identities maps names, GUIDs and IPs to canonical names.
+type Identities struct {
+	names map[string]string
+	guids map[string]string
+	ips   map[string]string
+}
+
+// New indexes identities, failing when a name, GUID or IP belongs to more
+// than one of them.
+func New(identities []Identity) (*Identities, error) {
+	ids := &Identities{names: map[string]string{}, guids: map[string]string{}, ips: map[string]string{}}
+	for _, identity := range identities {
+		if identity.Name == "" {
+			return nil, errors.New("Identity without a name")
+		}
+		add := func(index map[string]string, kind string, values []string) error {
+			for _, value := range values {
+				key := strings.ToLower(value)
+				if name, ok := index[key]; ok && name != identity.Name {
+					return fmt.Errorf("%s %q belongs to both %q and %q", kind, value, name, identity.Name)
+				}
+				index[key] = identity.Name
+			}
+			return nil
+		}
+		if err := add(ids.names, "Name", append([]string{identity.Name}, identity.Aliases...)); err != nil {
+			return nil, err
+		}
+		if err := add(ids.guids, "GUID", identity.GUIDs); err != nil {
+			return nil, err
+		}
+		if err := add(ids.ips, "IP", identity.IPs); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// Load reads the identities of the players key of a YAML, TOML or JSON
+// file, like:
+//
+//	players:
+//	  - name: Isgalamido
+//	    aliases: [Isga, isgalamido2]
+//	    guids: [8A9F03B1C2D4E5F6]
+//	    ips: [10.0.0.7]
+func Load(path string) (*Identities, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	identities := []Identity{}
+	if err := v.UnmarshalKey("players", &identities); err != nil {
+		return nil, err
+	}
+	return New(identities)
+}
+
+// Resolve returns the canonical name of player, looking it up by GUID,
+// then by IP and then by name, ignoring case. Players not found keep
+// their own name.
+func (ids *Identities) Resolve(player parser.Player) string {
+	if name, ok := ids.guids[strings.ToLower(player.GUID)]; ok && player.GUID != "" {
+		return name
+	}
+	if name, ok := ids.ips[strings.ToLower(player.IP)]; ok && player.IP != "" {
+		return name
+	}
+	if name, ok := ids.names[strings.ToLower(player.Name)]; ok {
+		return name
+	}
+	return player.Name
+}
+
+// Apply returns a copy of match with every player renamed to their
+// canonical name, so reports count them as the same player.
+func (ids *Identities) Apply(match parser.Match) parser.Match {
+	players := make([]parser.Player, len(match.Players))
+	for i, player := range match.Players {
+		player.Name = ids.Resolve(player)
+		players[i] = player
+	}
+	match.Players = players
+	return match
+}
+
+// Suggestion is a group of names that are likely the same player.
+type Suggestion struct {
+	Names  []string `json:"names"`
+	Reason string   `json:"reason"`
+}
+
+// Suggest looks for names in matches that are likely aliases of each other
+// and that ids don't resolve to the same player yet: names used from the
+// same GUID or IP, and names that differ only in case, by a prefix or by a
+// couple of characters and never played the same match.
+func (ids *Identities) Suggest(matches []parser.Match) []Suggestion {
+	byGUID := map[string]map[string]bool{}
+	byIP := map[string]map[string]bool{}
+	together := map[[2]string]bool{}
+	names := map[string]bool{}
+	group := func(index map[string]map[string]bool, key, name string) {
+		if key == "" {
+			return
+		}
+		if index[key] == nil {
+			index[key] = map[string]bool{}
+		}
+		index[key][name] = true
+	}
+	for _, match := range matches {
+		match = ids.Apply(match)
+		for i, player := range match.Players {
+			names[player.Name] = true
+			group(byGUID, player.GUID, player.Name)
+			group(byIP, player.IP, player.Name)
+			for _, other := range match.Players[i+1:] {
+				together[pair(player.Name, other.Name)] = true
+			}
+		}
+	}
+
+	suggestions := []Suggestion{}
+	seen := map[string]bool{}
+	suggest := func(names []string, reason string) {
+		canonical := map[string]bool{}
+		for _, name := range names {
+			canonical[ids.Resolve(parser.Player{Name: name})] = true
+		}
+		if len(canonical) < 2 {
+			return
+		}
+		sort.Strings(names)
+		key := strings.Join(names, "\x00")
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		suggestions = append(suggestions, Suggestion{Names: names, Reason: reason})
+	}
+	for _, index := range []struct {
+		groups map[string]map[string]bool
+		reason string
+	}{{byGUID, "Same GUID"}, {byIP, "Same IP"}} {
+		keys := make([]string, 0, len(index.groups))
+		for key := range index.groups {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			suggest(setKeys(index.groups[key]), index.reason)
+		}
+	}
+
+	sorted := setKeys(names)
+	for i, name := range sorted {
+		for _, other := range sorted[i+1:] {
+			if together[pair(name, other)] {
+				continue
+			}
+			if reason := similar(name, other); reason != "" {
+				suggest([]string{name, other}, reason)
+			}
+		}
+	}
+	return suggestions
+}
+
+// similar describes why two names look like the same one, or returns an
+// empty string when they don't.
+func similar(a, b string) string {
+	la, lb := strings.ToLower(a), strings.ToLower(b)
+	switch {
+	case la == lb:
+		return "Same name in a different case"
+	case len(la) >= 3 && len(lb) >= 3 && (strings.HasPrefix(la, lb) || strings.HasPrefix(lb, la)):
+		return "One name starts with the other"
+	case len(la) >= 5 && len(lb) >= 5 && distance(la, lb) <= 2:
+		return "Names differ by up to 2 characters"
+	}
+	return ""
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			next := min(row[j]+1, row[j-1]+1, prev+cost)
+			prev, row[j] = row[j], next
+		}
+	}
+	return row[len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, value := range values[1:] {
+		if value < m {
+			m = value
+		}
+	}
+	return m
+}
+
+func pair(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+func setKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
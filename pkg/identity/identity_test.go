@@ -0,0 +1,193 @@
+package identity_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/reesilva/quake-log/pkg/identity"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var _identities = []identity.Identity{
+	{
+		Name:    "Isgalamido",
+		Aliases: []string{"Isga"},
+		GUIDs:   []string{"8A9F03B1C2D4E5F6"},
+	},
+	{
+		Name: "Mocinha",
+		IPs:  []string{"10.0.0.7"},
+	},
+}
+
+func TestResolve(t *testing.T) {
+	ids, err := identity.New(_identities)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name   string
+		player parser.Player
+		want   string
+	}{
+		{
+			name:   "Alias",
+			player: parser.Player{ID: 2, Name: "isga"},
+			want:   "Isgalamido",
+		},
+		{
+			name:   "GUID before the name",
+			player: parser.Player{ID: 2, Name: "Mocinha", GUID: "8a9f03b1c2d4e5f6"},
+			want:   "Isgalamido",
+		},
+		{
+			name:   "IP",
+			player: parser.Player{ID: 3, Name: "Dono da Bola", IP: "10.0.0.7"},
+			want:   "Mocinha",
+		},
+		{
+			name:   "Unknown player",
+			player: parser.Player{ID: 4, Name: "Zeh"},
+			want:   "Zeh",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ids.Resolve(tt.player))
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	ids, err := identity.New(_identities)
+	assert.Nil(t, err)
+	match := parser.Match{
+		Players: []parser.Player{{ID: 2, Name: "Isga"}, {ID: 3, Name: "Zeh"}},
+		Events:  []parser.Kill{},
+	}
+	applied := ids.Apply(match)
+	assert.Equal(t, []parser.Player{{ID: 2, Name: "Isgalamido"}, {ID: 3, Name: "Zeh"}}, applied.Players)
+	assert.Equal(t, "Isga", match.Players[0].Name)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		identities []identity.Identity
+		err        error
+	}{
+		{
+			name:       "Identity without a name",
+			identities: []identity.Identity{{Aliases: []string{"Isga"}}},
+			err:        errors.New("Identity without a name"),
+		},
+		{
+			name: "Alias of two players",
+			identities: []identity.Identity{
+				{Name: "Isgalamido", Aliases: []string{"Isga"}},
+				{Name: "Mocinha", Aliases: []string{"isga"}},
+			},
+			err: errors.New(`Name "isga" belongs to both "Isgalamido" and "Mocinha"`),
+		},
+		{
+			name: "GUID of two players",
+			identities: []identity.Identity{
+				{Name: "Isgalamido", GUIDs: []string{"ABC"}},
+				{Name: "Mocinha", GUIDs: []string{"ABC"}},
+			},
+			err: errors.New(`GUID "ABC" belongs to both "Isgalamido" and "Mocinha"`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := identity.New(tt.identities)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "players.yaml")
+	content := "players:\n  - name: Isgalamido\n    aliases: [Isga]\n    guids: [8A9F03B1C2D4E5F6]\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := identity.Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "Isgalamido", ids.Resolve(parser.Player{Name: "Isga"}))
+	assert.Equal(t, "Isgalamido", ids.Resolve(parser.Player{Name: "Zeh", GUID: "8A9F03B1C2D4E5F6"}))
+
+	_, err = identity.Load(filepath.Join(dir, "missing.yaml"))
+	assert.NotNil(t, err)
+}
+
+func TestSuggest(t *testing.T) {
+	matches := []parser.Match{
+		{
+			Players: []parser.Player{
+				{ID: 2, Name: "Isgalamido", GUID: "ABC"},
+				{ID: 3, Name: "Mocinha", IP: "10.0.0.7"},
+				{ID: 4, Name: "Mocinho"},
+			},
+		},
+		{
+			Players: []parser.Player{
+				{ID: 2, Name: "Isgalamid0", GUID: "ABC"},
+				{ID: 3, Name: "Dono", IP: "10.0.0.7"},
+				{ID: 4, Name: "zeh"},
+			},
+		},
+		{
+			Players: []parser.Player{
+				{ID: 2, Name: "Zeh"},
+				{ID: 3, Name: "Isga"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		identities []identity.Identity
+		want       []identity.Suggestion
+	}{
+		{
+			name: "Aliases by GUID, IP and similar names",
+			want: []identity.Suggestion{
+				{Names: []string{"Isgalamid0", "Isgalamido"}, Reason: "Same GUID"},
+				{Names: []string{"Dono", "Mocinha"}, Reason: "Same IP"},
+				{Names: []string{"Isga", "Isgalamid0"}, Reason: "One name starts with the other"},
+				{Names: []string{"Isga", "Isgalamido"}, Reason: "One name starts with the other"},
+				{Names: []string{"Zeh", "zeh"}, Reason: "Same name in a different case"},
+			},
+		},
+		{
+			name: "Names that are already the same player",
+			identities: []identity.Identity{
+				{Name: "Isgalamido", Aliases: []string{"Isga"}, GUIDs: []string{"ABC"}},
+				{Name: "Zeh", Aliases: []string{"zeh"}},
+			},
+			want: []identity.Suggestion{
+				{Names: []string{"Dono", "Mocinha"}, Reason: "Same IP"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := identity.New(tt.identities)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, ids.Suggest(matches))
+		})
+	}
+}
@@ -0,0 +1,301 @@
+// Package input resolves and opens the Quake 3 Arena Server log files
+// given to the CLI.
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/ulikunitz/xz"
+)
+
+// Stdin is the path used to read a log from the standard input.
+const Stdin = "-"
+
+// Order of the log files returned by Expand.
+const (
+	// ByName orders the log files by their path.
+	ByName = "name"
+	// ByModTime orders the log files from the oldest modified to the newest.
+	ByModTime = "modtime"
+	// ByTime orders the log files by the server uptime logged on the
+	// InitGame line of their first match. It's the only time in a log,
+	// which has no date, so it only orders the logs of a server that
+	// wasn't restarted in between, like the ones split by hand. Files
+	// without a match are the last ones.
+	ByTime = "time"
+)
+
+// Expand resolves paths, which may be log files, glob patterns or
+// directories, into the log files to be read, without duplicates and in
+// the given order. Directories are walked recursively, skipping hidden
+// files. Stdin, if given, is always the last one.
+func Expand(paths []string, order string) ([]string, error) {
+	if order != ByName && order != ByModTime && order != ByTime {
+		return nil, fmt.Errorf("Unknown order %q", order)
+	}
+	files := []string{}
+	// keys are what files are ordered by, other than their path.
+	keys := map[string]int64{}
+	stdin := false
+	add := func(path string, info os.FileInfo) {
+		if _, ok := keys[path]; !ok {
+			keys[path] = info.ModTime().UnixNano()
+			files = append(files, path)
+		}
+	}
+
+	for _, pattern := range paths {
+		if pattern == Stdin {
+			stdin = true
+			continue
+		}
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(pattern); err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("No log files match %q", pattern)
+			}
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(filepath.Clean(match), info)
+				continue
+			}
+			err = filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if path != match && strings.HasPrefix(info.Name(), ".") {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if info.Mode().IsRegular() {
+					add(path, info)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if order == ByTime {
+		for _, path := range files {
+			start, err := firstMatch(path)
+			if err != nil {
+				return nil, err
+			}
+			keys[path] = start
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if order != ByName && keys[files[i]] != keys[files[j]] {
+			return keys[files[i]] < keys[files[j]]
+		}
+		return files[i] < files[j]
+	})
+	if stdin {
+		files = append(files, Stdin)
+	}
+	return files, nil
+}
+
+// firstMatch returns the server uptime, in nanoseconds, logged on the
+// first InitGame line of the log file in path, or math.MaxInt64 when it
+// has none.
+func firstMatch(path string) (int64, error) {
+	file, err := Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		timestamp, event, _, ok := parser.Tokenize(scanner.Text())
+		if !ok || event != "InitGame" {
+			continue
+		}
+		start, err := parser.ParseTimestamp(timestamp)
+		if err != nil {
+			return 0, err
+		}
+		return int64(start), nil
+	}
+	return math.MaxInt64, scanner.Err()
+}
+
+// _rotated splits the path of a log file rotated by logrotate, like
+// games.log.2.gz, in the path of the log, its number and its extension.
+var _rotated = regexp.MustCompile(`^(.*?)(?:\.(\d+))?(?:\.(gz|bz2|xz|zst))?$`)
+
+// Series is a log file along with its rotated copies, which together are
+// a single log that must be read in order so matches are not split.
+type Series struct {
+	// Name is the path of the log file, without the rotation number or
+	// the compression extension.
+	Name string
+	// Files are the log files of the series, from the oldest to the newest.
+	Files []string
+}
+
+// Group puts the log files rotated from the same log, like games.log,
+// games.log.1 and games.log.2.gz, in a single Series. The series are
+// returned in the order their first file appears in paths.
+func Group(paths []string) []Series {
+	series := []Series{}
+	byName := map[string]int{}
+	numbers := map[string]int{}
+	for _, path := range paths {
+		name, number := path, -1
+		if path != Stdin {
+			parts := _rotated.FindStringSubmatch(path)
+			name = parts[1]
+			if parts[2] != "" {
+				number, _ = strconv.Atoi(parts[2])
+			}
+		}
+		numbers[path] = number
+		if i, ok := byName[name]; ok {
+			series[i].Files = append(series[i].Files, path)
+			continue
+		}
+		byName[name] = len(series)
+		series = append(series, Series{Name: name, Files: []string{path}})
+	}
+	for _, s := range series {
+		files := s.Files
+		sort.SliceStable(files, func(i, j int) bool {
+			return numbers[files[i]] > numbers[files[j]]
+		})
+	}
+	return series
+}
+
+// Open opens the log file in path, or the standard input for Stdin. Logs
+// compressed with gzip, bzip2, xz or zstd are detected by their magic
+// bytes and decompressed on the fly.
+func Open(path string) (io.ReadCloser, error) {
+	return OpenCounted(path, nil)
+}
+
+// OpenCounted works like Open, but adds to count, if not nil, the bytes
+// read from the file before they are decompressed, so they can be compared
+// to its size.
+func OpenCounted(path string, count *int64) (io.ReadCloser, error) {
+	var file io.ReadCloser = ioutil.NopCloser(os.Stdin)
+	if path != Stdin {
+		var err error
+		if file, err = os.Open(path); err != nil {
+			return nil, err
+		}
+	}
+	var raw io.Reader = file
+	if count != nil {
+		raw = CountingReader{Reader: file, Count: count}
+	}
+	reader := bufio.NewReader(raw)
+	decompressed, err := decompress(reader)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return readCloser{Reader: decompressed, closers: []io.Closer{decompressed, file}}, nil
+}
+
+// Compressed tells whether the log file in path is compressed.
+func Compressed(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	return compression(bufio.NewReader(file)) != "", nil
+}
+
+func compression(reader *bufio.Reader) string {
+	magic, _ := reader.Peek(6)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return "gzip"
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return "bzip2"
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return "xz"
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "zstd"
+	}
+	return ""
+}
+
+// decompress wraps reader with the decompressor of its format, if any.
+// The returned reader must be closed.
+func decompress(reader *bufio.Reader) (io.ReadCloser, error) {
+	switch compression(reader) {
+	case "gzip":
+		return gzip.NewReader(reader)
+	case "bzip2":
+		return ioutil.NopCloser(bzip2.NewReader(reader)), nil
+	case "xz":
+		r, err := xz.NewReader(reader)
+		return ioutil.NopCloser(r), err
+	case "zstd":
+		r, err := zstd.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		return r.IOReadCloser(), nil
+	}
+	return ioutil.NopCloser(reader), nil
+}
+
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r readCloser) Close() error {
+	var err error
+	for _, closer := range r.closers {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// CountingReader adds to Count the bytes read from Reader.
+type CountingReader struct {
+	io.Reader
+	Count *int64
+}
+
+func (r CountingReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	*r.Count += int64(n)
+	return n, err
+}
@@ -0,0 +1,252 @@
+package input_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/reesilva/quake-log/pkg/input"
+	"github.com/stretchr/testify/assert"
+	"github.com/ulikunitz/xz"
+)
+
+func TestExpand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := []string{
+		"server2/games.log",
+		"server1/games.log",
+		"server1/old/games.log",
+		"server1/.hidden.log",
+		"server1/.git/HEAD",
+		"single.log",
+	}
+	// Uptimes of the first match of each file, for the order by time.
+	contents := map[string]string{
+		"server2/games.log":     "------\n  5:00 InitGame: \\mapname\\q3dm17\n",
+		"server1/games.log":     " 10:00 InitGame: \\mapname\\q3dm17\n",
+		"server1/old/games.log": "  1:00 InitGame: \\mapname\\q3dm17\n",
+	}
+	for i, file := range files {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents[file]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Date(2020, 12, 1, 0, 0, len(files)-i, 0, time.UTC)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	join := func(paths ...string) []string {
+		joined := []string{}
+		for _, path := range paths {
+			if path == input.Stdin {
+				joined = append(joined, path)
+				continue
+			}
+			joined = append(joined, filepath.Join(dir, path))
+		}
+		return joined
+	}
+
+	tests := []struct {
+		name          string
+		paths         []string
+		order         string
+		want          []string
+		expectError   bool
+		expectedError string
+	}{
+		{
+			name:  "Files",
+			paths: join("single.log", "server2/games.log", "single.log"),
+			order: input.ByName,
+			want:  join("server2/games.log", "single.log"),
+		},
+		{
+			name:  "Glob",
+			paths: join("server*/games.log"),
+			order: input.ByName,
+			want:  join("server1/games.log", "server2/games.log"),
+		},
+		{
+			name:  "Directory",
+			paths: join("server1"),
+			order: input.ByName,
+			want:  join("server1/games.log", "server1/old/games.log"),
+		},
+		{
+			name:  "Stdin is the last one",
+			paths: append([]string{input.Stdin}, join("single.log")...),
+			order: input.ByName,
+			want:  join("single.log", input.Stdin),
+		},
+		{
+			name:  "By modification time",
+			paths: join("server1", "server2", "single.log"),
+			order: input.ByModTime,
+			want:  join("single.log", "server1/old/games.log", "server1/games.log", "server2/games.log"),
+		},
+		{
+			name:  "By time of the first match",
+			paths: join("server1", "server2", "single.log"),
+			order: input.ByTime,
+			want:  join("server1/old/games.log", "server2/games.log", "server1/games.log", "single.log"),
+		},
+		{
+			name:          "Glob without matches",
+			paths:         []string{"missing*.log"},
+			order:         input.ByName,
+			expectError:   true,
+			expectedError: `No log files match "missing*.log"`,
+		},
+		{
+			name:          "Unknown order",
+			paths:         join("single.log"),
+			order:         "size",
+			expectError:   true,
+			expectedError: `Unknown order "size"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := input.Expand(tt.paths, tt.order)
+			if tt.expectError {
+				if assert.Error(t, err) {
+					expected := errors.New(tt.expectedError)
+					assert.Equal(t, expected, err)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+
+	_, err = input.Expand(join("missing.log"), input.ByName)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// _bzip2Log is the content of _plainLog compressed by bzip2, since the
+// standard library can only decompress it.
+var _bzip2Log = []byte{0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x77, 0x0c, 0x4e, 0xef, 0x00, 0x00, 0x00, 0xdf, 0x80, 0x00, 0x10, 0x40, 0x00, 0x68, 0x90, 0x00, 0xa0, 0x00, 0x04, 0x26, 0x23, 0x64, 0x00, 0x20, 0x00, 0x31, 0x4c, 0x98, 0x99, 0x06, 0x46, 0x11, 0x3d, 0x11, 0xa0, 0x1a, 0x62, 0x58, 0x1a, 0x28, 0x06, 0xd1, 0x76, 0x59, 0x52, 0x4d, 0xc6, 0x38, 0x08, 0xdf, 0xce, 0x53, 0x06, 0x93, 0xf1, 0x77, 0x24, 0x53, 0x85, 0x09, 0x07, 0x70, 0xc4, 0xee, 0xf0}
+
+const _plainLog = "  0:00 InitGame: \\mapname\\q3dm17\n"
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	compress := map[string]func(io.Writer) io.WriteCloser{
+		"games.log.1.gz": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"games.log.2.xz": func(w io.Writer) io.WriteCloser {
+			writer, _ := xz.NewWriter(w)
+			return writer
+		},
+		"games.log.3.zst": func(w io.Writer) io.WriteCloser {
+			writer, _ := zstd.NewWriter(w)
+			return writer
+		},
+	}
+	for name, newWriter := range compress {
+		buffer := &bytes.Buffer{}
+		writer := newWriter(buffer)
+		if _, err := writer.Write([]byte(_plainLog)); err != nil {
+			t.Fatal(err)
+		}
+		writer.Close()
+		if err := ioutil.WriteFile(filepath.Join(dir, name), buffer.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "games.log.4.bz2"), _bzip2Log, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "games.log"), []byte(_plainLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"games.log", "games.log.1.gz", "games.log.2.xz", "games.log.3.zst", "games.log.4.bz2"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			reader, err := input.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := ioutil.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.NoError(t, reader.Close())
+			assert.Equal(t, _plainLog, string(content))
+
+			compressed, err := input.Compressed(path)
+			assert.NoError(t, err)
+			assert.Equal(t, name != "games.log", compressed)
+
+			var count int64
+			reader, err = input.OpenCounted(path, &count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = ioutil.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.NoError(t, reader.Close())
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, info.Size(), count)
+		})
+	}
+}
+
+func TestCountingReader(t *testing.T) {
+	count := int64(3)
+	content, err := ioutil.ReadAll(input.CountingReader{Reader: bytes.NewBufferString("  0:00 InitGame:"), Count: &count})
+	assert.Nil(t, err)
+	assert.Equal(t, "  0:00 InitGame:", string(content))
+	assert.Equal(t, int64(19), count)
+}
+
+func TestGroup(t *testing.T) {
+	got := input.Group([]string{
+		"a/games.log",
+		"a/games.log.1",
+		"a/games.log.10.gz",
+		"a/games.log.2.gz",
+		"b/games.log.1.xz",
+		"b/server.log",
+		input.Stdin,
+	})
+	assert.Equal(t, []input.Series{
+		{
+			Name:  "a/games.log",
+			Files: []string{"a/games.log.10.gz", "a/games.log.2.gz", "a/games.log.1", "a/games.log"},
+		},
+		{
+			Name:  "b/games.log",
+			Files: []string{"b/games.log.1.xz"},
+		},
+		{
+			Name:  "b/server.log",
+			Files: []string{"b/server.log"},
+		},
+		{
+			Name:  input.Stdin,
+			Files: []string{input.Stdin},
+		},
+	}, got)
+}
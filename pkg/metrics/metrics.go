@@ -0,0 +1,167 @@
+// Package metrics exports the activity of Quake 3 Arena Servers, as
+// parsed from their logs, as Prometheus metrics.
+package metrics
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+)
+
+// Metrics counts lines, parse errors and events of each log file, which
+// is used as the source label of every metric.
+type Metrics struct {
+	registry         *prometheus.Registry
+	linesProcessed   *prometheus.CounterVec
+	parseErrors      *prometheus.CounterVec
+	kills            *prometheus.CounterVec
+	matchesStarted   *prometheus.CounterVec
+	matchesEnded     *prometheus.CounterVec
+	connectedPlayers *prometheus.GaugeVec
+
+	mu      sync.Mutex
+	players map[string]map[int]bool
+	offsets map[string]int64
+}
+
+// New returns Metrics with every collector registered in its own registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		linesProcessed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quake_log_lines_processed_total",
+			Help: "Lines read from the log file.",
+		}, []string{"source"}),
+		parseErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quake_log_parse_errors_total",
+			Help: "Lines of the log file that could not be parsed.",
+		}, []string{"source"}),
+		kills: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quake_log_kills_total",
+			Help: "Kills by mean of death.",
+		}, []string{"source", "weapon"}),
+		matchesStarted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quake_log_matches_started_total",
+			Help: "Matches started, by InitGame.",
+		}, []string{"source"}),
+		matchesEnded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quake_log_matches_ended_total",
+			Help: "Matches ended, by ShutdownGame.",
+		}, []string{"source"}),
+		connectedPlayers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "quake_log_connected_players",
+			Help: "Players connected to the match being played.",
+		}, []string{"source"}),
+		players: map[string]map[int]bool{},
+		offsets: map[string]int64{},
+	}
+	m.registry.MustRegister(
+		m.linesProcessed,
+		m.parseErrors,
+		m.kills,
+		m.matchesStarted,
+		m.matchesEnded,
+		m.connectedPlayers,
+		&lagCollector{metrics: m},
+	)
+	return m
+}
+
+// Handler returns the http.Handler serving the metrics to Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Line counts a line read from source and, if err is not nil, the
+// error parsing it.
+func (m *Metrics) Line(source string, err error) {
+	m.linesProcessed.WithLabelValues(source).Inc()
+	if err != nil {
+		m.parseErrors.WithLabelValues(source).Inc()
+	}
+}
+
+// Progress records the offset in source of the next byte to be read,
+// which is used to report how far behind the end of the file it is.
+func (m *Metrics) Progress(source string, offset int64) {
+	m.mu.Lock()
+	m.offsets[source] = offset
+	m.mu.Unlock()
+}
+
+// Event updates the metrics with an event parsed from source.
+func (m *Metrics) Event(source string, event parser.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch event.Name {
+	case "InitGame":
+		m.matchesStarted.WithLabelValues(source).Inc()
+		m.players[source] = map[int]bool{}
+	case "ShutdownGame":
+		m.matchesEnded.WithLabelValues(source).Inc()
+		m.players[source] = map[int]bool{}
+	case "ClientConnect", "ClientDisconnect":
+		playerID, err := strconv.Atoi(strings.TrimSpace(event.Payload))
+		if err != nil {
+			return
+		}
+		if m.players[source] == nil {
+			m.players[source] = map[int]bool{}
+		}
+		if event.Name == "ClientConnect" {
+			m.players[source][playerID] = true
+		} else {
+			delete(m.players[source], playerID)
+		}
+	case "Kill":
+		if len(event.Match.Events) == 0 {
+			return
+		}
+		kill := event.Match.Events[len(event.Match.Events)-1]
+		m.kills.WithLabelValues(source, output.Weapon(kill)).Inc()
+		return
+	default:
+		return
+	}
+	m.connectedPlayers.WithLabelValues(source).Set(float64(len(m.players[source])))
+}
+
+// lagCollector reports, when scraped, how many bytes each log file has
+// beyond what was already read.
+type lagCollector struct {
+	metrics *Metrics
+}
+
+var _lagDesc = prometheus.NewDesc(
+	"quake_log_lag_bytes",
+	"Bytes written to the log file that were not read yet.",
+	[]string{"source"}, nil,
+)
+
+func (c *lagCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- _lagDesc
+}
+
+func (c *lagCollector) Collect(ch chan<- prometheus.Metric) {
+	c.metrics.mu.Lock()
+	offsets := map[string]int64{}
+	for source, offset := range c.metrics.offsets {
+		offsets[source] = offset
+	}
+	c.metrics.mu.Unlock()
+
+	for source, offset := range offsets {
+		lag := int64(0)
+		if info, err := os.Stat(source); err == nil && info.Size() > offset {
+			lag = info.Size() - offset
+		}
+		ch <- prometheus.MustNewConstMetric(_lagDesc, prometheus.GaugeValue, float64(lag), source)
+	}
+}
@@ -0,0 +1,81 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/reesilva/quake-log/pkg/metrics"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) []string {
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	samples := []string{}
+	for _, line := range strings.Split(recorder.Body.String(), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			samples = append(samples, line)
+		}
+	}
+	return samples
+}
+
+func TestMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "games.log")
+	if err := ioutil.WriteFile(source, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := metrics.New()
+	stream := parser.Stream{
+		OnEvent: func(event parser.Event) error {
+			m.Event(source, event)
+			return nil
+		},
+	}
+	lines := []string{
+		`  0:00 InitGame: \mapname\q3dm17`,
+		" 20:34 ClientConnect: 2",
+		" 20:34 ClientConnect: 3",
+		" 20:35 ClientConnect: 4",
+		" 20:36 ClientConnect: 4",
+		" 20:36 ClientConnect: 5",
+		" 20:37 ClientDisconnect: 5",
+		" 20:40 Kill: 1022 2 22: <world> killed Isgalamido by MOD_TRIGGER_HURT",
+		" 20:41 Kill: 3 2 10: Mocinha killed Isgalamido by MOD_RAILGUN",
+		" 20:42 Kill: 9 2 10: Nobody killed Isgalamido by MOD_RAILGUN",
+		" 20:43 Kill: 3 2 10: Mocinha killed Isgalamido by MOD_RAILGUN",
+	}
+	for _, line := range lines {
+		m.Line(source, stream.ParseLine(line))
+	}
+	m.Progress(source, 4)
+
+	assert.Equal(t, []string{
+		`quake_log_connected_players{source="` + source + `"} 3`,
+		`quake_log_kills_total{source="` + source + `",weapon="MOD_RAILGUN"} 2`,
+		`quake_log_kills_total{source="` + source + `",weapon="MOD_TRIGGER_HURT"} 1`,
+		`quake_log_lag_bytes{source="` + source + `"} 6`,
+		`quake_log_lines_processed_total{source="` + source + `"} 11`,
+		`quake_log_matches_started_total{source="` + source + `"} 1`,
+		`quake_log_parse_errors_total{source="` + source + `"} 1`,
+	}, scrape(t, m))
+
+	m.Line(source, stream.ParseLine(" 20:50 ShutdownGame:"))
+	m.Progress(source, 10)
+	samples := scrape(t, m)
+	assert.Contains(t, samples, `quake_log_connected_players{source="`+source+`"} 0`)
+	assert.Contains(t, samples, `quake_log_matches_ended_total{source="`+source+`"} 1`)
+	assert.Contains(t, samples, `quake_log_lag_bytes{source="`+source+`"} 0`)
+}
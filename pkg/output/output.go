@@ -1,7 +1,11 @@
 package output
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/reesilva/quake-log/pkg/parser"
 )
@@ -14,6 +18,44 @@ type MatchReport struct {
 	KillsByMeans map[string]int `json:"kills_by_means"`
 }
 
+// Equal reports whether r and other have the same kills, by the same
+// players and means of death. Players are compared in any order and
+// missing counts are 0, so missing and empty maps are equal.
+func (r MatchReport) Equal(other MatchReport) bool {
+	return r.TotalKills == other.TotalKills &&
+		equalPlayers(r.Players, other.Players) &&
+		equalCounts(r.Kills, other.Kills) &&
+		equalCounts(r.KillsByMeans, other.KillsByMeans)
+}
+
+func equalPlayers(a, b []string) bool {
+	inA, inB := map[string]bool{}, map[string]bool{}
+	for _, player := range a {
+		inA[player] = true
+	}
+	for _, player := range b {
+		if !inA[player] {
+			return false
+		}
+		inB[player] = true
+	}
+	return len(inA) == len(inB)
+}
+
+func equalCounts(a, b map[string]int) bool {
+	for key, value := range a {
+		if b[key] != value {
+			return false
+		}
+	}
+	for key, value := range b {
+		if a[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
 var _meansOfDeath []string = []string{
 	"MOD_UNKNOWN",
 	"MOD_SHOTGUN",
@@ -46,55 +88,198 @@ var _meansOfDeath []string = []string{
 	"MOD_GRAPPLE",
 }
 
+// MatchEntry is a MatchReport identified by the log file it came from, its
+// position in it, the map where it was played, when it started and a
+// content hash.
+type MatchEntry struct {
+	// Server is the name of the server the match was played on, if set.
+	Server    string `json:"server,omitempty"`
+	Source    string `json:"source,omitempty"`
+	ID        string `json:"id"`
+	Index     int    `json:"index"`
+	Map       string `json:"map"`
+	StartTime string `json:"start_time"`
+	// Bots are the players of the match that are bots.
+	Bots []string `json:"bots,omitempty"`
+	MatchReport
+	// Hits and Assists are the hits and the assists to kills made by each
+	// player, for the dialects that log them, like Urban Terror.
+	Hits    map[string]int `json:"hits,omitempty"`
+	Assists map[string]int `json:"assists,omitempty"`
+	// Damage is the damage dealt by each player and ExitMessage why the
+	// match ended, for the dialects that log the stats of the match, like
+	// Quake Live.
+	Damage      map[string]int `json:"damage,omitempty"`
+	ExitMessage string         `json:"exit_message,omitempty"`
+	// Scores are the scores of the players by the rules of a Scoring, if
+	// they were asked for.
+	Scores map[string]int `json:"scores,omitempty"`
+	// Timeline is how the scores progressed, if it was asked for.
+	Timeline *Timeline `json:"timeline,omitempty"`
+	// Awards are the achievements won in the match, if they were asked for.
+	Awards []Award `json:"awards,omitempty"`
+}
+
+// Award is an achievement won by the players of a match with the same
+// value of the stat it's given by, more than one when tied.
+type Award struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Players     []string `json:"players"`
+	Value       int      `json:"value"`
+}
+
 // CreateMatchReport receives a slice of Parser.Match itens and a boolean to define if
 // it will also create an object of death by means and then will return a map of
 // output.MatchReport or an error if something brakes.
 func CreateMatchReport(matches []parser.Match, deathByMeans bool) (map[string]MatchReport, error) {
 	matchesReport := map[string]MatchReport{}
-	if len(matches) == 0 {
-		return matchesReport, nil
+	for key, value := range matches {
+		matchesReport[fmt.Sprintf("game_%d", key+1)] = createReport(value, deathByMeans)
 	}
+	return matchesReport, nil
+}
+
+// CreateMatchList works like CreateMatchReport but returns the reports
+// in the same order the matches were played, each one as an output.MatchEntry.
+func CreateMatchList(matches []parser.Match, deathByMeans bool) ([]MatchEntry, error) {
+	entries := []MatchEntry{}
+	ids := MatchIDs{}
 	for key, value := range matches {
-		players := []string{}
-		for _, playersValue := range value.Players {
-			playerIndex := parser.FindUserByID(value.Players, playersValue.ID)
-			players = append(players, value.Players[playerIndex].Name)
+		entry := CreateMatchEntry(key+1, value, deathByMeans)
+		entry.ID = ids.ID(key+1, value)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// CreateMatchEntry builds the output.MatchEntry of a single match, where
+// index is the 1-based position of the match in the log file.
+func CreateMatchEntry(index int, match parser.Match, deathByMeans bool) MatchEntry {
+	entry := MatchEntry{
+		ID:          MatchID(match),
+		Index:       index,
+		Map:         match.MapName(),
+		StartTime:   FormatTimestamp(match.StartTime),
+		MatchReport: createReport(match, deathByMeans),
+	}
+	for _, player := range match.Players {
+		if player.IsBot {
+			entry.Bots = append(entry.Bots, player.Name)
 		}
-		if len(value.Events) == 0 {
-			matchesReport[fmt.Sprintf("game_%d", key+1)] = MatchReport{
-				TotalKills: 0,
-				Players:    players,
-				Kills:      map[string]int{},
-			}
-			continue
+	}
+	count := func(counts *map[string]int, id int, at time.Duration, n int) {
+		index := match.PlayerAt(id, at)
+		if index == -1 {
+			return
+		}
+		if *counts == nil {
+			*counts = map[string]int{}
 		}
-		matchesReport[fmt.Sprintf("game_%d", key+1)] = MatchReport{
-			TotalKills: len(value.Events),
-			Players:    players,
-			Kills:      map[string]int{},
+		(*counts)[match.Players[index].Name] += n
+	}
+	for _, hit := range match.Hits {
+		count(&entry.Hits, hit.AttackerID, hit.Time, 1)
+	}
+	for _, assist := range match.Assists {
+		count(&entry.Assists, assist.AssistantID, assist.Time, 1)
+	}
+	for _, stats := range match.PlayerStats {
+		count(&entry.Damage, stats.PlayerID, stats.Time, stats.DamageDealt)
+	}
+	if match.MatchStats != nil {
+		entry.ExitMessage = match.MatchStats.ExitMessage
+	}
+	return entry
+}
+
+// MatchID returns a deterministic hash of the contents of a match, so the
+// same match will always have the same ID no matter where it is in the log,
+// or how the log file is named. Use MatchIDs to tell apart the matches of a
+// log with identical contents.
+func MatchID(match parser.Match) string {
+	return matchID(0, match)
+}
+
+// MatchIDs gives IDs to the matches of a log. A match gets its MatchID,
+// unless a match with identical contents, like two empty ones, got it at
+// another index of the log before. Then its 1-based index in the log is
+// hashed along with its contents. The zero value is ready to use.
+type MatchIDs struct {
+	// indexes maps the MatchID of each match seen to its index.
+	indexes map[string]int
+}
+
+// ID returns the ID of the match found at the 1-based index of the log.
+func (ids *MatchIDs) ID(index int, match parser.Match) string {
+	id := MatchID(match)
+	if ids.indexes == nil {
+		ids.indexes = map[string]int{}
+	}
+	if seen, ok := ids.indexes[id]; ok && seen != index {
+		return matchID(index, match)
+	}
+	ids.indexes[id] = index
+	return id
+}
+
+// matchID hashes the contents of a match and, unless it's 0, its index.
+func matchID(index int, match parser.Match) string {
+	h := sha256.New()
+	if index != 0 {
+		fmt.Fprintf(h, "index:%d\n", index)
+	}
+	fmt.Fprintf(h, "start:%d\n", match.StartTime)
+	keys := make([]string, 0, len(match.Settings))
+	for key := range match.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(h, "setting:%q=%q\n", key, match.Settings[key])
+	}
+	for _, player := range match.Players {
+		fmt.Fprintf(h, "player:%d=%q\n", player.ID, player.Name)
+	}
+	for _, event := range match.Events {
+		fmt.Fprintf(h, "kill:%d %d %d\n", event.KillerID, event.VictimID, event.MeanOfDeath)
+		if event.Weapon != "" {
+			fmt.Fprintf(h, "weapon:%q\n", event.Weapon)
 		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// FormatTimestamp formats a duration the same way the Quake 3 Arena Server
+// log does, as minutes and seconds of uptime.
+func FormatTimestamp(d time.Duration) string {
+	seconds := int(d / time.Second)
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+func createReport(match parser.Match, deathByMeans bool) MatchReport {
+	players := []string{}
+	for _, playersValue := range match.Players {
+		players = append(players, playersValue.Name)
+	}
+	report := MatchReport{
+		TotalKills: len(match.Events),
+		Players:    players,
+		Kills:      map[string]int{},
+	}
+	if len(match.Events) == 0 {
+		return report
+	}
+	if deathByMeans {
+		report.KillsByMeans = map[string]int{}
+	}
+	for _, eventValue := range match.Events {
 		if deathByMeans {
-			report := matchesReport[fmt.Sprintf("game_%d", key+1)]
-			report.KillsByMeans = map[string]int{}
-			matchesReport[fmt.Sprintf("game_%d", key+1)] = report
+			report.KillsByMeans[Weapon(eventValue)]++
 		}
-		for _, eventValue := range value.Events {
-			if deathByMeans {
-				if val, ok := matchesReport[fmt.Sprintf("game_%d", key+1)].KillsByMeans[_meansOfDeath[eventValue.MeanOfDeath]]; ok {
-					matchesReport[fmt.Sprintf("game_%d", key+1)].KillsByMeans[_meansOfDeath[eventValue.MeanOfDeath]] = val + 1
-				} else {
-					matchesReport[fmt.Sprintf("game_%d", key+1)].KillsByMeans[_meansOfDeath[eventValue.MeanOfDeath]] = 1
-				}
-			}
-			if eventValue.KillerID != 1022 {
-				killerIndex := parser.FindUserByID(value.Players, eventValue.KillerID)
-				if val, ok := matchesReport[fmt.Sprintf("game_%d", key+1)].Kills[value.Players[killerIndex].Name]; ok {
-					matchesReport[fmt.Sprintf("game_%d", key+1)].Kills[value.Players[killerIndex].Name] = val + 1
-				} else {
-					matchesReport[fmt.Sprintf("game_%d", key+1)].Kills[value.Players[killerIndex].Name] = 1
-				}
-			}
+		if killerIndex := match.Killer(eventValue); killerIndex != -1 {
+			report.Kills[match.Players[killerIndex].Name]++
 		}
 	}
-	return matchesReport, nil
+	return report
 }
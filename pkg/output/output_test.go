@@ -3,6 +3,7 @@ package output_test
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/reesilva/quake-log/pkg/output"
 	"github.com/reesilva/quake-log/pkg/parser"
@@ -780,3 +781,270 @@ func TestCreateMatchReport(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateMatchList(t *testing.T) {
+	matches := []parser.Match{
+		{
+			Players: []parser.Player{
+				{
+					ID:   2,
+					Name: "Isgalamido",
+				},
+				{
+					ID:   3,
+					Name: "Mocinha",
+				},
+			},
+			Events: []parser.Kill{
+				{
+					KillerID:    2,
+					VictimID:    3,
+					MeanOfDeath: 22,
+				},
+				{
+					KillerID:    1022,
+					VictimID:    2,
+					MeanOfDeath: 22,
+				},
+			},
+			StartTime: 20*time.Minute + 37*time.Second,
+			Settings: map[string]string{
+				"mapname": "q3dm17",
+			},
+		},
+		{
+			Players: []parser.Player{},
+			Events:  []parser.Kill{},
+		},
+	}
+	got, err := output.CreateMatchList(matches, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []output.MatchEntry{
+		{
+			ID:        output.MatchID(matches[0]),
+			Index:     1,
+			Map:       "q3dm17",
+			StartTime: "20:37",
+			MatchReport: output.MatchReport{
+				TotalKills: 2,
+				Players:    []string{"Isgalamido", "Mocinha"},
+				Kills: map[string]int{
+					"Isgalamido": 1,
+				},
+				KillsByMeans: map[string]int{
+					"MOD_TRIGGER_HURT": 2,
+				},
+			},
+		},
+		{
+			ID:        output.MatchID(matches[1]),
+			Index:     2,
+			Map:       "",
+			StartTime: "0:00",
+			MatchReport: output.MatchReport{
+				TotalKills: 0,
+				Players:    []string{},
+				Kills:      map[string]int{},
+			},
+		},
+	}, got)
+
+	empty, err := output.CreateMatchList([]parser.Match{}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []output.MatchEntry{}, empty)
+}
+
+func TestMatchID(t *testing.T) {
+	match := parser.Match{
+		Players: []parser.Player{
+			{
+				ID:   2,
+				Name: "Isgalamido",
+			},
+		},
+		Events:    []parser.Kill{},
+		StartTime: 15 * time.Second,
+		Settings: map[string]string{
+			"mapname":     "q3dm17",
+			"sv_hostname": "Code Miner Server",
+		},
+	}
+	id := output.MatchID(match)
+	assert.Len(t, id, 16)
+
+	sameContent := parser.Match{
+		Players:   []parser.Player{{ID: 2, Name: "Isgalamido"}},
+		StartTime: 15 * time.Second,
+		Settings: map[string]string{
+			"sv_hostname": "Code Miner Server",
+			"mapname":     "q3dm17",
+		},
+	}
+	assert.Equal(t, id, output.MatchID(sameContent))
+
+	otherStart := sameContent
+	otherStart.StartTime = 16 * time.Second
+	assert.NotEqual(t, id, output.MatchID(otherStart))
+}
+
+func TestMatchIDs(t *testing.T) {
+	empty := parser.Match{Settings: map[string]string{"mapname": "q3dm17"}}
+	played := parser.Match{
+		Players:  []parser.Player{{ID: 2, Name: "Isgalamido"}},
+		Settings: map[string]string{"mapname": "q3dm17"},
+	}
+
+	ids := output.MatchIDs{}
+	first := ids.ID(1, empty)
+	assert.Equal(t, output.MatchID(empty), first)
+	assert.Equal(t, output.MatchID(played), ids.ID(2, played))
+	third := ids.ID(3, empty)
+	assert.NotEqual(t, first, third)
+	assert.Equal(t, first, ids.ID(1, empty))
+
+	// The log parsed again after its first match was rotated away: the
+	// other matches keep their IDs, whatever their new index.
+	rotated := output.MatchIDs{}
+	assert.Equal(t, output.MatchID(played), rotated.ID(1, played))
+	assert.Equal(t, first, rotated.ID(2, empty))
+
+	again := output.MatchIDs{}
+	assert.Equal(t, first, again.ID(1, empty))
+	assert.Equal(t, third, again.ID(3, empty))
+}
+
+func TestCreateMatchEntryBots(t *testing.T) {
+	match := parser.Match{
+		Players: []parser.Player{
+			{ID: 2, Name: "Isgalamido"},
+			{ID: 4, Name: "Sarge", IsBot: true},
+		},
+		Events:   []parser.Kill{{KillerID: 4, VictimID: 2, MeanOfDeath: 6}},
+		Settings: map[string]string{"mapname": "q3dm17"},
+	}
+
+	entry := output.CreateMatchEntry(1, match, false)
+	assert.Equal(t, []string{"Sarge"}, entry.Bots)
+	assert.Equal(t, map[string]int{"Sarge": 1}, entry.Kills)
+
+	entry = output.CreateMatchEntry(1, match.WithoutBots(), false)
+	assert.Nil(t, entry.Bots)
+	assert.Equal(t, []string{"Isgalamido"}, entry.Players)
+	assert.Equal(t, 0, entry.TotalKills)
+}
+
+func TestCreateMatchEntrySlotReuse(t *testing.T) {
+	lines := []string{
+		`  0:00 InitGame: \mapname\q3dm17`,
+		" 0:01 ClientConnect: 2",
+		` 0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0`,
+		" 0:02 ClientConnect: 3",
+		` 0:02 ClientUserinfoChanged: 3 n\Mocinha\t\0`,
+		" 0:10 Kill: 2 3 10: Isgalamido killed Mocinha by MOD_RAILGUN",
+		" 0:15 Kill: 2 3 10: Isgalamido killed Mocinha by MOD_RAILGUN",
+		" 0:20 ClientDisconnect: 3",
+		" 0:30 ClientConnect: 3",
+		` 0:30 ClientUserinfoChanged: 3 n\Mocinha\t\0`,
+		" 0:35 ClientDisconnect: 2",
+		" 0:36 ClientConnect: 2",
+		` 0:36 ClientUserinfoChanged: 2 n\Zeh\t\0`,
+		" 0:40 Kill: 2 3 6: Zeh killed Mocinha by MOD_ROCKET",
+	}
+	var match parser.Match
+	stream := parser.Stream{OnMatchEnd: func(index int, m parser.Match) error {
+		match = m
+		return nil
+	}}
+	for _, line := range lines {
+		assert.NoError(t, stream.ParseLine(line))
+	}
+	assert.NoError(t, stream.Close())
+
+	// Like before players were indexed by slot, the report lists each
+	// connection, so a player who reconnects is listed again.
+	entry := output.CreateMatchEntry(1, match, false)
+	assert.Equal(t, []string{"Isgalamido", "Mocinha", "Mocinha", "Zeh"}, entry.Players)
+	assert.Equal(t, map[string]int{"Isgalamido": 2, "Zeh": 1}, entry.Kills)
+}
+
+func TestMatchReportEqual(t *testing.T) {
+	report := output.MatchReport{TotalKills: 1, Players: []string{"Isgalamido", "Mocinha"}, Kills: map[string]int{"Isgalamido": 1}}
+	tests := []struct {
+		name  string
+		other output.MatchReport
+		equal bool
+	}{
+		{
+			name:  "Same report with empty means of death",
+			other: output.MatchReport{TotalKills: 1, Players: []string{"Isgalamido", "Mocinha"}, Kills: map[string]int{"Isgalamido": 1}, KillsByMeans: map[string]int{}},
+			equal: true,
+		},
+		{
+			name:  "Same players in another order",
+			other: output.MatchReport{TotalKills: 1, Players: []string{"Mocinha", "Isgalamido"}, Kills: map[string]int{"Isgalamido": 1}},
+			equal: true,
+		},
+		{
+			name:  "Same kills with counts of 0",
+			other: output.MatchReport{TotalKills: 1, Players: []string{"Isgalamido", "Mocinha"}, Kills: map[string]int{"Isgalamido": 1, "Mocinha": 0}},
+			equal: true,
+		},
+		{
+			name:  "Other kills",
+			other: output.MatchReport{TotalKills: 1, Players: []string{"Isgalamido", "Mocinha"}, Kills: map[string]int{"Mocinha": 1}},
+		},
+		{
+			name:  "Other players",
+			other: output.MatchReport{TotalKills: 1, Players: []string{"Isgalamido"}, Kills: map[string]int{"Isgalamido": 1}},
+		},
+		{
+			name:  "Another player instead of one",
+			other: output.MatchReport{TotalKills: 1, Players: []string{"Isgalamido", "Zeh"}, Kills: map[string]int{"Isgalamido": 1}},
+		},
+		{
+			name:  "Other means of death",
+			other: output.MatchReport{TotalKills: 1, Players: []string{"Isgalamido", "Mocinha"}, Kills: map[string]int{"Isgalamido": 1}, KillsByMeans: map[string]int{"MOD_RAILGUN": 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.equal, report.Equal(tt.other))
+			assert.Equal(t, tt.equal, tt.other.Equal(report))
+		})
+	}
+}
+
+func TestCreateMatchEntryDialectStats(t *testing.T) {
+	match := parser.Match{
+		Players: []parser.Player{
+			{ID: 0, Name: "Isgalamido"},
+			{ID: 1, Name: "Mocinha"},
+			{ID: 2, Name: "Dono.da.Bola"},
+		},
+		Hits: []parser.Hit{
+			{AttackerID: 0, VictimID: 1, Location: 2, Weapon: 19},
+			{AttackerID: 0, VictimID: 2, Location: 1, Weapon: 19},
+			{AttackerID: 2, VictimID: 0, Location: 1, Weapon: 14},
+			{AttackerID: 7, VictimID: 0, Location: 1, Weapon: 14},
+		},
+		Assists: []parser.Assist{{AssistantID: 2, KillerID: 1, VictimID: 0}},
+		PlayerStats: []parser.PlayerStats{
+			{PlayerID: 0, Name: "Isgalamido", DamageDealt: 100},
+			{PlayerID: 1, Name: "Mocinha", DamageDealt: 0},
+			{PlayerID: -1, Name: "Zeh", DamageDealt: 50},
+		},
+		MatchStats: &parser.MatchStats{ExitMessage: "Timelimit hit."},
+	}
+
+	entry := output.CreateMatchEntry(1, match, false)
+	assert.Equal(t, map[string]int{"Isgalamido": 2, "Dono.da.Bola": 1}, entry.Hits)
+	assert.Equal(t, map[string]int{"Dono.da.Bola": 1}, entry.Assists)
+	assert.Equal(t, map[string]int{"Isgalamido": 100, "Mocinha": 0}, entry.Damage)
+	assert.Equal(t, "Timelimit hit.", entry.ExitMessage)
+
+	entry = output.CreateMatchEntry(1, parser.Match{Players: match.Players}, false)
+	assert.Nil(t, entry.Hits)
+	assert.Nil(t, entry.Assists)
+	assert.Nil(t, entry.Damage)
+}
@@ -0,0 +1,75 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+)
+
+// _worldID is the client slot of the world in the kills it makes.
+const _worldID = 1022
+
+// Scoring are the points a player scores by each kind of kill, like the
+// classic rules of a kill by a player worth 1 and a death by the world or
+// a suicide worth -1.
+type Scoring struct {
+	// Kill is scored by the killer of another player.
+	Kill int `json:"kill"`
+	// Suicide is scored by a player that killed themselves.
+	Suicide int `json:"suicide"`
+	// World is scored by a player killed by the world.
+	World int `json:"world"`
+}
+
+// ParseScoring returns the Scoring of points keyed by kill, suicide and
+// world. The kinds of kill left out score nothing.
+func ParseScoring(points map[string]int) (Scoring, error) {
+	scoring := Scoring{}
+	for kind, value := range points {
+		switch kind {
+		case "kill":
+			scoring.Kill = value
+		case "suicide":
+			scoring.Suicide = value
+		case "world":
+			scoring.World = value
+		default:
+			return Scoring{}, fmt.Errorf("Unknown scoring %q, expected kill, suicide or world", kind)
+		}
+	}
+	return scoring, nil
+}
+
+// CreateScores returns the score of every player of match by the rules of
+// scoring, 0 for the players that scored nothing. A player who reconnects
+// keeps their score.
+func CreateScores(match parser.Match, scoring Scoring) map[string]int {
+	scores := map[string]int{}
+	for _, player := range match.Players {
+		scores[player.Name] = 0
+	}
+	for _, kill := range match.Events {
+		if name, points := scoring.score(match, kill); name != "" {
+			scores[name] += points
+		}
+	}
+	return scores
+}
+
+// score returns the player of match that scores by kill, and the points
+// they score, or an empty name when no player of the match does.
+func (s Scoring) score(match parser.Match, kill parser.Kill) (string, int) {
+	victim := match.Victim(kill)
+	killer := match.Killer(kill)
+	switch {
+	case victim == -1:
+	case kill.KillerID == _worldID:
+		return match.Players[victim].Name, s.World
+	case killer == -1:
+	case match.Players[killer].Name == match.Players[victim].Name:
+		return match.Players[victim].Name, s.Suicide
+	default:
+		return match.Players[killer].Name, s.Kill
+	}
+	return "", 0
+}
@@ -0,0 +1,91 @@
+package output_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateScores(t *testing.T) {
+	match := parser.Match{
+		Players: []parser.Player{
+			{ID: 2, Name: "Isgalamido"},
+			{ID: 3, Name: "Mocinha"},
+			{ID: 4, Name: "Zeh"},
+		},
+		Events: []parser.Kill{
+			{KillerID: 2, VictimID: 3, MeanOfDeath: 10},
+			{KillerID: 2, VictimID: 4, MeanOfDeath: 10},
+			{KillerID: 1022, VictimID: 2, MeanOfDeath: 22},
+			{KillerID: 3, VictimID: 3, MeanOfDeath: 7},
+			{KillerID: 5, VictimID: 4, MeanOfDeath: 10},
+		},
+	}
+	tests := []struct {
+		name    string
+		scoring output.Scoring
+		scores  map[string]int
+	}{
+		{
+			name:    "Kills only",
+			scoring: output.Scoring{Kill: 1},
+			scores:  map[string]int{"Isgalamido": 2, "Mocinha": 0, "Zeh": 0},
+		},
+		{
+			name:    "Kills, suicides and deaths by the world",
+			scoring: output.Scoring{Kill: 2, Suicide: -1, World: -1},
+			scores:  map[string]int{"Isgalamido": 3, "Mocinha": -1, "Zeh": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.scores, output.CreateScores(match, tt.scoring))
+		})
+	}
+}
+
+func TestParseScoring(t *testing.T) {
+	tests := []struct {
+		name    string
+		points  map[string]int
+		scoring output.Scoring
+		err     error
+	}{
+		{
+			name:    "Every kind of kill",
+			points:  map[string]int{"kill": 1, "suicide": -1, "world": -2},
+			scoring: output.Scoring{Kill: 1, Suicide: -1, World: -2},
+		},
+		{
+			name:    "Kinds of kill left out",
+			points:  map[string]int{"kill": 1},
+			scoring: output.Scoring{Kill: 1},
+		},
+		{
+			name:   "Unknown kind of kill",
+			points: map[string]int{"assist": 1},
+			err:    errors.New(`Unknown scoring "assist", expected kill, suicide or world`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scoring, err := output.ParseScoring(tt.points)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.scoring, scoring)
+		})
+	}
+}
+
+func TestSetMeanOfDeath(t *testing.T) {
+	output.SetMeanOfDeath(30, "MOD_HOOK")
+	defer output.SetMeanOfDeath(30, "MOD_UNKNOWN")
+	assert.Equal(t, "MOD_HOOK", output.MeanOfDeath(30))
+	assert.Equal(t, "MOD_UNKNOWN", output.MeanOfDeath(29))
+	assert.Equal(t, "MOD_UNKNOWN", output.MeanOfDeath(31))
+	assert.Equal(t, "MOD_RAILGUN", output.MeanOfDeath(10))
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"github.com/reesilva/quake-log/pkg/parser"
+)
+
+// PlayerStats aggregates the kills and deaths of a player over many matches.
+type PlayerStats struct {
+	Name    string `json:"name"`
+	Matches int    `json:"matches"`
+	Kills   int    `json:"kills"`
+	Deaths  int    `json:"deaths"`
+}
+
+// MeanOfDeath returns the name of a Quake 3 Arena mean of death, like
+// MOD_RAILGUN, or MOD_UNKNOWN when the id is not known.
+func MeanOfDeath(id int) string {
+	if id < 0 || id >= len(_meansOfDeath) {
+		return _meansOfDeath[0]
+	}
+	return _meansOfDeath[id]
+}
+
+// SetMeanOfDeath names the mean of death id, like the ones added by mods to
+// the table of Quake 3 Arena, or renames a known one. It's not safe to call
+// while matches are reported.
+func SetMeanOfDeath(id int, name string) {
+	for id >= len(_meansOfDeath) {
+		_meansOfDeath = append(_meansOfDeath, _meansOfDeath[0])
+	}
+	_meansOfDeath[id] = name
+}
+
+// Weapon returns the name of the mean of death of a kill: the one logged,
+// for dialects that number them differently, or the one of baseq3.
+func Weapon(kill parser.Kill) string {
+	if kill.Weapon != "" {
+		return kill.Weapon
+	}
+	return MeanOfDeath(kill.MeanOfDeath)
+}
+
+// CreatePlayerStats receives a slice of parser.Match and returns the stats
+// of every player keyed by name. Kills are counted the same way
+// CreateMatchReport does and kills by the world count as deaths.
+func CreatePlayerStats(matches []parser.Match) map[string]PlayerStats {
+	stats := map[string]PlayerStats{}
+	for _, match := range matches {
+		played := map[string]bool{}
+		for _, player := range match.Players {
+			if played[player.Name] {
+				continue
+			}
+			played[player.Name] = true
+			stat := stats[player.Name]
+			stat.Name = player.Name
+			stat.Matches++
+			stats[player.Name] = stat
+		}
+		for _, event := range match.Events {
+			if killerIndex := match.Killer(event); killerIndex != -1 {
+				stat := stats[match.Players[killerIndex].Name]
+				stat.Kills++
+				stats[match.Players[killerIndex].Name] = stat
+			}
+			if victimIndex := match.Victim(event); victimIndex != -1 {
+				stat := stats[match.Players[victimIndex].Name]
+				stat.Deaths++
+				stats[match.Players[victimIndex].Name] = stat
+			}
+		}
+	}
+	return stats
+}
+
+// CreateWeaponStats receives a slice of parser.Match and returns how many
+// kills were made by each mean of death in all of them.
+func CreateWeaponStats(matches []parser.Match) map[string]int {
+	stats := map[string]int{}
+	for _, match := range matches {
+		for _, event := range match.Events {
+			stats[Weapon(event)]++
+		}
+	}
+	return stats
+}
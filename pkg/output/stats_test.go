@@ -0,0 +1,94 @@
+package output_test
+
+import (
+	"testing"
+
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var statsMatches = []parser.Match{
+	{
+		Players: []parser.Player{
+			{
+				ID:   2,
+				Name: "Isgalamido",
+			},
+			{
+				ID:   3,
+				Name: "Mocinha",
+			},
+			{
+				ID:   3,
+				Name: "Mocinha",
+			},
+		},
+		Events: []parser.Kill{
+			{
+				KillerID:    2,
+				VictimID:    3,
+				MeanOfDeath: 10,
+			},
+			{
+				KillerID:    1022,
+				VictimID:    2,
+				MeanOfDeath: 22,
+			},
+		},
+	},
+	{
+		Players: []parser.Player{
+			{
+				ID:   4,
+				Name: "Isgalamido",
+			},
+		},
+		Events: []parser.Kill{
+			{
+				KillerID:    4,
+				VictimID:    4,
+				MeanOfDeath: 7,
+			},
+		},
+	},
+}
+
+func TestMeanOfDeath(t *testing.T) {
+	assert.Equal(t, "MOD_RAILGUN", output.MeanOfDeath(10))
+	assert.Equal(t, "MOD_GRAPPLE", output.MeanOfDeath(28))
+	assert.Equal(t, "MOD_UNKNOWN", output.MeanOfDeath(29))
+	assert.Equal(t, "MOD_UNKNOWN", output.MeanOfDeath(-1))
+}
+
+func TestWeapon(t *testing.T) {
+	assert.Equal(t, "MOD_RAILGUN", output.Weapon(parser.Kill{MeanOfDeath: 10}))
+	assert.Equal(t, "UT_MOD_LR300", output.Weapon(parser.Kill{MeanOfDeath: 10, Weapon: "UT_MOD_LR300"}))
+}
+
+func TestCreatePlayerStats(t *testing.T) {
+	assert.Equal(t, map[string]output.PlayerStats{}, output.CreatePlayerStats([]parser.Match{}))
+	assert.Equal(t, map[string]output.PlayerStats{
+		"Isgalamido": {
+			Name:    "Isgalamido",
+			Matches: 2,
+			Kills:   2,
+			Deaths:  2,
+		},
+		"Mocinha": {
+			Name:    "Mocinha",
+			Matches: 1,
+			Kills:   0,
+			Deaths:  1,
+		},
+	}, output.CreatePlayerStats(statsMatches))
+}
+
+func TestCreateWeaponStats(t *testing.T) {
+	assert.Equal(t, map[string]int{}, output.CreateWeaponStats([]parser.Match{}))
+	assert.Equal(t, map[string]int{
+		"MOD_RAILGUN":       1,
+		"MOD_TRIGGER_HURT":  1,
+		"MOD_ROCKET_SPLASH": 1,
+	}, output.CreateWeaponStats(statsMatches))
+}
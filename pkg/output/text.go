@@ -0,0 +1,90 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteText writes entry to w as text for people to read, like:
+//
+//	Game 2 on q3dm17 at 20:37, games.log
+//	Total kills: 4
+//	Players: Isgalamido, Mocinha
+//	Kills:
+//	  Isgalamido  2
+//	Awards:
+//	  Survivor, Fewest deaths: Isgalamido (1)
+//
+// The counts are listed from the highest, tied ones by name, and the stats
+// of the dialect, timeline, scores and awards only when the entry has them. Matches end with
+// a blank line, so many can be written one after the other.
+func WriteText(w io.Writer, entry MatchEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	title := fmt.Sprintf("Game %d", entry.Index)
+	if entry.Map != "" {
+		title += " on " + entry.Map
+	}
+	if entry.StartTime != "" {
+		title += " at " + entry.StartTime
+	}
+	for _, from := range []string{entry.Source, entry.Server} {
+		if from != "" {
+			title += ", " + from
+		}
+	}
+	fmt.Fprintln(tw, title)
+	fmt.Fprintf(tw, "Total kills: %d\n", entry.TotalKills)
+	fmt.Fprintf(tw, "Players: %s\n", strings.Join(entry.Players, ", "))
+	if len(entry.Bots) > 0 {
+		fmt.Fprintf(tw, "Bots: %s\n", strings.Join(entry.Bots, ", "))
+	}
+	writeCounts(tw, "Kills", entry.Kills)
+	writeCounts(tw, "Kills by means", entry.KillsByMeans)
+	writeCounts(tw, "Hits", entry.Hits)
+	writeCounts(tw, "Assists", entry.Assists)
+	writeCounts(tw, "Damage", entry.Damage)
+	if entry.ExitMessage != "" {
+		fmt.Fprintf(tw, "Ended by: %s\n", entry.ExitMessage)
+	}
+	writeCounts(tw, "Scores", entry.Scores)
+	if entry.Timeline != nil && entry.Timeline.Winner != "" {
+		fmt.Fprintf(tw, "Winner: %s, leading since %s\n", entry.Timeline.Winner, entry.Timeline.WinnerLeadTime)
+	}
+	if len(entry.Awards) > 0 {
+		fmt.Fprintln(tw, "Awards:")
+		for _, award := range entry.Awards {
+			name := award.Name
+			if award.Description != "" {
+				name += ", " + award.Description
+			}
+			fmt.Fprintf(tw, "  %s: %s (%d)\n", name, strings.Join(award.Players, ", "), award.Value)
+		}
+	}
+	fmt.Fprintln(tw)
+	return tw.Flush()
+}
+
+// writeCounts writes the counts under title, from the highest, or nothing
+// when there are none.
+func writeCounts(w io.Writer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(w, "  %s\t%d\n", key, counts[key])
+	}
+}
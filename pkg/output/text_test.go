@@ -0,0 +1,84 @@
+package output_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteText(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry output.MatchEntry
+		text  string
+	}{
+		{
+			name: "Match with no events",
+			entry: output.MatchEntry{
+				Index:       1,
+				Map:         "q3dm17",
+				StartTime:   "0:00",
+				MatchReport: output.MatchReport{Players: []string{"Isgalamido"}, Kills: map[string]int{}},
+			},
+			text: "Game 1 on q3dm17 at 0:00\n" +
+				"Total kills: 0\n" +
+				"Players: Isgalamido\n" +
+				"\n",
+		},
+		{
+			name: "Match with kills, scores and awards",
+			entry: output.MatchEntry{
+				Server:    "Code Miner",
+				Source:    "games.log",
+				Index:     2,
+				Map:       "q3dm17",
+				StartTime: "20:37",
+				MatchReport: output.MatchReport{
+					TotalKills:   4,
+					Players:      []string{"Isgalamido", "Mocinha"},
+					Kills:        map[string]int{"Isgalamido": 2},
+					KillsByMeans: map[string]int{"MOD_TRIGGER_HURT": 2, "MOD_RAILGUN": 1, "MOD_ROCKET_SPLASH": 1},
+				},
+				Damage:      map[string]int{"Isgalamido": 300, "Mocinha": 1200},
+				ExitMessage: "Timelimit hit.",
+				Scores:      map[string]int{"Isgalamido": 0, "Mocinha": 0},
+				Timeline:    &output.Timeline{Winner: "Isgalamido", WinnerLeadTime: "21:07"},
+				Awards: []output.Award{
+					{Name: "Survivor", Description: "Fewest deaths", Players: []string{"Mocinha"}, Value: 0},
+					{Name: "Railgunner", Players: []string{"Isgalamido"}, Value: 1},
+				},
+			},
+			text: "Game 2 on q3dm17 at 20:37, games.log, Code Miner\n" +
+				"Total kills: 4\n" +
+				"Players: Isgalamido, Mocinha\n" +
+				"Kills:\n" +
+				"  Isgalamido  2\n" +
+				"Kills by means:\n" +
+				"  MOD_TRIGGER_HURT   2\n" +
+				"  MOD_RAILGUN        1\n" +
+				"  MOD_ROCKET_SPLASH  1\n" +
+				"Damage:\n" +
+				"  Mocinha     1200\n" +
+				"  Isgalamido  300\n" +
+				"Ended by: Timelimit hit.\n" +
+				"Scores:\n" +
+				"  Isgalamido  0\n" +
+				"  Mocinha     0\n" +
+				"Winner: Isgalamido, leading since 21:07\n" +
+				"Awards:\n" +
+				"  Survivor, Fewest deaths: Mocinha (0)\n" +
+				"  Railgunner: Isgalamido (1)\n" +
+				"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			assert.Nil(t, output.WriteText(&b, tt.entry))
+			assert.Equal(t, tt.text, b.String())
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package output
+
+import (
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+)
+
+// Timeline is how the scores of a match progressed. Scores are counted
+// the same way as the kills of its MatchReport or, when the match is
+// scored, by the same Scoring of its scores.
+type Timeline struct {
+	// Samples are the scores of every player over the match.
+	Samples []ScoreSample `json:"samples"`
+	// LeadChanges are the moments a player took the lead from another one,
+	// or from a tie at the start. A tie doesn't end a lead, so the player
+	// that goes ahead again doesn't take it twice.
+	LeadChanges []LeadChange `json:"lead_changes"`
+	// Winner is the player with the highest score at the end, if not tied.
+	Winner string `json:"winner,omitempty"`
+	// WinnerLeadTime is when the winner took the lead for good, without
+	// being tied or passed again.
+	WinnerLeadTime string `json:"winner_lead_time,omitempty"`
+}
+
+// ScoreSample are the scores of every player at a moment of the match.
+type ScoreSample struct {
+	// Time is the server uptime, formatted as in the log, and Elapsed the
+	// time since the match started.
+	Time    string         `json:"time"`
+	Elapsed string         `json:"elapsed"`
+	Scores  map[string]int `json:"scores"`
+}
+
+// LeadChange is a player taking the lead with a kill.
+type LeadChange struct {
+	Time    string `json:"time"`
+	Elapsed string `json:"elapsed"`
+	Leader  string `json:"leader"`
+	Score   int    `json:"score"`
+}
+
+// CreateTimeline returns the timeline of match with a sample every
+// interval since the match started, and one more when it ended, or with a
+// sample after every kill if interval is zero. Samples have the kills
+// logged up to the second of their time. The match ends at its
+// ShutdownGame or, without one, at its last kill. Kills are scored by the
+// rules of scoring, if not nil, like CreateScores does.
+func CreateTimeline(match parser.Match, interval time.Duration, scoring *Scoring) Timeline {
+	timeline := Timeline{
+		Samples:     []ScoreSample{},
+		LeadChanges: []LeadChange{},
+	}
+	scores := map[string]int{}
+	for _, player := range match.Players {
+		scores[player.Name] = 0
+	}
+	sample := func(at time.Duration) {
+		copied := make(map[string]int, len(scores))
+		for name, score := range scores {
+			copied[name] = score
+		}
+		timeline.Samples = append(timeline.Samples, ScoreSample{
+			Time:    FormatTimestamp(at),
+			Elapsed: FormatTimestamp(at - match.StartTime),
+			Scores:  copied,
+		})
+	}
+
+	end := match.EndTime
+	next := match.StartTime + interval
+	// leader leads since the kill at leadSince, and lastLeader is the last
+	// player that took the lead, even if tied since then.
+	leader, lastLeader := "", ""
+	var leadSince time.Duration
+	for _, kill := range match.Events {
+		// Kills without a time, like the ones saved before kills had one,
+		// are at the start.
+		at := kill.Time
+		if at < match.StartTime {
+			at = match.StartTime
+		}
+		if match.EndTime == 0 && at > end {
+			end = at
+		}
+		for interval > 0 && next < at {
+			sample(next)
+			next += interval
+		}
+		switch {
+		case scoring != nil:
+			if name, points := scoring.score(match, kill); name != "" {
+				scores[name] += points
+			}
+		default:
+			if index := match.Killer(kill); index != -1 {
+				scores[match.Players[index].Name]++
+			}
+		}
+		if interval == 0 {
+			sample(at)
+		}
+		if current := soleLeader(scores); current != leader {
+			leader, leadSince = current, at
+		}
+		if leader != "" && leader != lastLeader {
+			lastLeader = leader
+			timeline.LeadChanges = append(timeline.LeadChanges, LeadChange{
+				Time:    FormatTimestamp(at),
+				Elapsed: FormatTimestamp(at - match.StartTime),
+				Leader:  leader,
+				Score:   scores[leader],
+			})
+		}
+	}
+	for interval > 0 && next < end {
+		sample(next)
+		next += interval
+	}
+	if interval > 0 && end > match.StartTime {
+		sample(end)
+	}
+
+	if leader != "" {
+		timeline.Winner = leader
+		timeline.WinnerLeadTime = FormatTimestamp(leadSince)
+	}
+	return timeline
+}
+
+// soleLeader returns the player with the highest score, or an empty
+// string when it's tied or no one scored.
+func soleLeader(scores map[string]int) string {
+	leader, best, tied := "", 0, false
+	for name, score := range scores {
+		switch {
+		case leader == "" || score > best:
+			leader, best, tied = name, score, false
+		case score == best:
+			tied = true
+		}
+	}
+	if tied || best == 0 {
+		return ""
+	}
+	return leader
+}
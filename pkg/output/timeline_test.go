@@ -0,0 +1,157 @@
+package output_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var timelineMatch = parser.Match{
+	Players: []parser.Player{
+		{ID: 2, Name: "Isgalamido"},
+		{ID: 3, Name: "Mocinha"},
+	},
+	Events: []parser.Kill{
+		{KillerID: 2, VictimID: 3, MeanOfDeath: 10, Time: time.Minute + 10*time.Second},
+		{KillerID: 3, VictimID: 2, MeanOfDeath: 10, Time: time.Minute + 50*time.Second},
+		{KillerID: 3, VictimID: 2, MeanOfDeath: 6, Time: 2 * time.Minute},
+		{KillerID: 1022, VictimID: 3, MeanOfDeath: 22, Time: 2*time.Minute + 30*time.Second},
+		{KillerID: 2, VictimID: 3, MeanOfDeath: 10, Time: 2*time.Minute + 40*time.Second},
+		{KillerID: 2, VictimID: 3, MeanOfDeath: 10, Time: 3 * time.Minute},
+	},
+	StartTime: time.Minute,
+	EndTime:   3*time.Minute + 30*time.Second,
+}
+
+func TestCreateTimeline(t *testing.T) {
+	tied := timelineMatch
+	tied.Events = tied.Events[:2]
+	tied.EndTime = 0
+
+	leadChanges := []output.LeadChange{
+		{Time: "1:10", Elapsed: "0:10", Leader: "Isgalamido", Score: 1},
+		{Time: "2:00", Elapsed: "1:00", Leader: "Mocinha", Score: 2},
+		{Time: "3:00", Elapsed: "2:00", Leader: "Isgalamido", Score: 3},
+	}
+	tests := []struct {
+		name     string
+		match    parser.Match
+		interval time.Duration
+		scoring  *output.Scoring
+		want     output.Timeline
+	}{
+		{
+			name:     "Scores every minute and at the end",
+			match:    timelineMatch,
+			interval: time.Minute,
+			want: output.Timeline{
+				Samples: []output.ScoreSample{
+					{Time: "2:00", Elapsed: "1:00", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 2}},
+					{Time: "3:00", Elapsed: "2:00", Scores: map[string]int{"Isgalamido": 3, "Mocinha": 2}},
+					{Time: "3:30", Elapsed: "2:30", Scores: map[string]int{"Isgalamido": 3, "Mocinha": 2}},
+				},
+				LeadChanges:    leadChanges,
+				Winner:         "Isgalamido",
+				WinnerLeadTime: "3:00",
+			},
+		},
+		{
+			name:  "Scores after every kill",
+			match: timelineMatch,
+			want: output.Timeline{
+				Samples: []output.ScoreSample{
+					{Time: "1:10", Elapsed: "0:10", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 0}},
+					{Time: "1:50", Elapsed: "0:50", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 1}},
+					{Time: "2:00", Elapsed: "1:00", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 2}},
+					{Time: "2:30", Elapsed: "1:30", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 2}},
+					{Time: "2:40", Elapsed: "1:40", Scores: map[string]int{"Isgalamido": 2, "Mocinha": 2}},
+					{Time: "3:00", Elapsed: "2:00", Scores: map[string]int{"Isgalamido": 3, "Mocinha": 2}},
+				},
+				LeadChanges:    leadChanges,
+				Winner:         "Isgalamido",
+				WinnerLeadTime: "3:00",
+			},
+		},
+		{
+			name:    "Scores by the scoring of the match",
+			match:   timelineMatch,
+			scoring: &output.Scoring{Kill: 1, Suicide: -1, World: -1},
+			want: output.Timeline{
+				Samples: []output.ScoreSample{
+					{Time: "1:10", Elapsed: "0:10", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 0}},
+					{Time: "1:50", Elapsed: "0:50", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 1}},
+					{Time: "2:00", Elapsed: "1:00", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 2}},
+					{Time: "2:30", Elapsed: "1:30", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 1}},
+					{Time: "2:40", Elapsed: "1:40", Scores: map[string]int{"Isgalamido": 2, "Mocinha": 1}},
+					{Time: "3:00", Elapsed: "2:00", Scores: map[string]int{"Isgalamido": 3, "Mocinha": 1}},
+				},
+				LeadChanges: []output.LeadChange{
+					{Time: "1:10", Elapsed: "0:10", Leader: "Isgalamido", Score: 1},
+					{Time: "2:00", Elapsed: "1:00", Leader: "Mocinha", Score: 2},
+					{Time: "2:40", Elapsed: "1:40", Leader: "Isgalamido", Score: 2},
+				},
+				Winner:         "Isgalamido",
+				WinnerLeadTime: "2:40",
+			},
+		},
+		{
+			name:     "Tied at the last kill",
+			match:    tied,
+			interval: time.Minute,
+			want: output.Timeline{
+				Samples: []output.ScoreSample{
+					{Time: "1:50", Elapsed: "0:50", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 1}},
+				},
+				LeadChanges: leadChanges[:1],
+			},
+		},
+		{
+			name: "Kills without a time",
+			match: parser.Match{
+				Players:   []parser.Player{{ID: 2, Name: "Isgalamido"}},
+				Events:    []parser.Kill{{KillerID: 2, VictimID: 2, MeanOfDeath: 7}},
+				StartTime: time.Minute,
+			},
+			want: output.Timeline{
+				Samples: []output.ScoreSample{
+					{Time: "1:00", Elapsed: "0:00", Scores: map[string]int{"Isgalamido": 1}},
+				},
+				LeadChanges:    []output.LeadChange{{Time: "1:00", Elapsed: "0:00", Leader: "Isgalamido", Score: 1}},
+				Winner:         "Isgalamido",
+				WinnerLeadTime: "1:00",
+			},
+		},
+		{
+			name: "Kills by a player that is not in the match",
+			match: parser.Match{
+				Players:   []parser.Player{{ID: 2, Name: "Isgalamido"}},
+				Events:    []parser.Kill{{KillerID: 5, VictimID: 2, MeanOfDeath: 7, Time: time.Minute}},
+				StartTime: time.Minute,
+			},
+			want: output.Timeline{
+				Samples: []output.ScoreSample{
+					{Time: "1:00", Elapsed: "0:00", Scores: map[string]int{"Isgalamido": 0}},
+				},
+				LeadChanges: []output.LeadChange{},
+			},
+		},
+		{
+			name:     "Match without kills",
+			match:    parser.Match{Players: []parser.Player{}, Events: []parser.Kill{}},
+			interval: time.Minute,
+			want: output.Timeline{
+				Samples:     []output.ScoreSample{},
+				LeadChanges: []output.LeadChange{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, output.CreateTimeline(tt.match, tt.interval, tt.scoring))
+		})
+	}
+}
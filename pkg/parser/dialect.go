@@ -0,0 +1,353 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect is the log syntax of a game built on id Tech 3. Every dialect
+// parses its log into the same Match, so reports don't depend on the game.
+type Dialect interface {
+	// Name identifies the dialect, like baseq3 or urt.
+	Name() string
+	// Detect reports whether a match, by the settings of its InitGame
+	// line, was played on the dialect's game.
+	Detect(settings map[string]string) bool
+	// Registry returns the handlers of the dialect's events.
+	Registry() *Registry
+}
+
+type dialect struct {
+	name     string
+	detect   func(settings map[string]string) bool
+	registry *Registry
+}
+
+func (d *dialect) Name() string {
+	return d.name
+}
+
+func (d *dialect) Detect(settings map[string]string) bool {
+	return d.detect(settings)
+}
+
+func (d *dialect) Registry() *Registry {
+	return d.registry
+}
+
+var (
+	// Baseq3 is the log of Quake 3 Arena, as written by ioquake3. Its
+	// Registry is DefaultRegistry.
+	Baseq3 Dialect = &dialect{
+		name:     "baseq3",
+		detect:   func(settings map[string]string) bool { return true },
+		registry: DefaultRegistry,
+	}
+	// OpenArena is the log of OpenArena. Player names keep the characters
+	// that baseq3 leaves out, like the ^ of color codes, and kills keep the
+	// name of their weapon.
+	OpenArena Dialect = &dialect{
+		name: "openarena",
+		detect: func(settings map[string]string) bool {
+			return strings.EqualFold(settings["gamename"], "baseoa") ||
+				strings.Contains(strings.ToLower(settings["version"]), "+oa")
+		},
+		registry: newNamedRegistry(),
+	}
+	// UrbanTerror is the log of Urban Terror, whose weapons are numbered
+	// differently of baseq3 and which logs every Hit and Assist.
+	UrbanTerror Dialect = &dialect{
+		name: "urt",
+		detect: func(settings map[string]string) bool {
+			gamename := strings.ToLower(settings["gamename"])
+			return strings.HasPrefix(gamename, "q3ut") || strings.HasPrefix(gamename, "q3urt") ||
+				strings.Contains(strings.ToLower(settings["version"]), "urt")
+		},
+		registry: newUrbanTerrorRegistry(),
+	}
+	// QuakeLive is the log of Quake Live, whose weapons are numbered
+	// differently of baseq3. The stats of the players and of the match,
+	// which Quake Live sends over ZeroMQ, are read when they are logged too,
+	// as PLAYER_STATS and MATCH_REPORT lines with the JSON of their
+	// events, before the ShutdownGame of the match.
+	QuakeLive Dialect = &dialect{
+		name: "quakelive",
+		detect: func(settings map[string]string) bool {
+			return strings.EqualFold(settings["gamename"], "baseqz") ||
+				strings.Contains(strings.ToLower(settings["version"]), "quakelive")
+		},
+		registry: newQuakeLiveRegistry(),
+	}
+)
+
+// Dialects are the dialects known by the parser, in the order they are
+// detected. Baseq3 is the last one, since it matches any log.
+var Dialects = []Dialect{UrbanTerror, QuakeLive, OpenArena, Baseq3}
+
+// LookupDialect returns the dialect called name.
+func LookupDialect(name string) (Dialect, error) {
+	names := []string{}
+	for _, d := range Dialects {
+		if strings.EqualFold(d.Name(), name) {
+			return d, nil
+		}
+		names = append(names, d.Name())
+	}
+	return nil, fmt.Errorf("Unknown dialect %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// DetectDialect returns the first of Dialects that detects a match by the
+// settings of its InitGame line.
+func DetectDialect(settings map[string]string) Dialect {
+	for _, d := range Dialects {
+		if d.Detect(settings) {
+			return d
+		}
+	}
+	return Baseq3
+}
+
+// newNamedRegistry returns the handlers of baseq3, but reading the whole
+// player names and the weapon of each kill.
+func newNamedRegistry() *Registry {
+	r := NewRegistry()
+	r.Register("ClientUserinfoChanged", handleNamedUserinfoChanged)
+	r.Register("Kill", handleNamedKill)
+	return r
+}
+
+func newUrbanTerrorRegistry() *Registry {
+	r := newNamedRegistry()
+	r.Register("Hit", handleHit)
+	r.Register("Assist", handleAssist)
+	return r
+}
+
+func newQuakeLiveRegistry() *Registry {
+	r := newNamedRegistry()
+	r.Register("PLAYER_STATS", MatchHandler(handlePlayerStats))
+	r.Register("MATCH_REPORT", MatchHandler(handleMatchReport))
+	return r
+}
+
+// handleNamedUserinfoChanged reads the name of a payload like
+// "2 n\^1Isga|lamido\t\0" up to the next backslash.
+func handleNamedUserinfoChanged(timestamp time.Duration, payload string, slc *[]Match, gameID int) error {
+	if len((*slc)) == 0 {
+		return errors.New("Updating player with no matches running")
+	}
+	if len((*slc)[gameID].Players) == 0 {
+		return errors.New("Updating player with no players on match")
+	}
+	space := skipDigits(payload, 0)
+	if space == 0 || !strings.HasPrefix(payload[space:], ` n\`) {
+		return errors.New("Error on Parse Line")
+	}
+	name := payload[space+3:]
+	if end := strings.IndexByte(name, '\\'); end != -1 {
+		name = name[:end]
+	}
+	if name == "" {
+		return errors.New("Error on Parse Line")
+	}
+	userID, _ := strconv.Atoi(payload[:space])
+	userIndex := (*slc)[gameID].PlayerIndex(userID)
+	if userIndex == -1 {
+		return errors.New("Trying to update a user that doesn't exists")
+	}
+	(*slc)[gameID].Players[userIndex].Name = name
+	updateUserinfo(&(*slc)[gameID].Players[userIndex], ParseInfoString(payload[space+1:]))
+	return nil
+}
+
+// handleNamedKill keeps the weapon logged after "by" in a payload like
+// "3 2 19: Mocinha killed Isgalamido by UT_MOD_LR300".
+func handleNamedKill(timestamp time.Duration, payload string, slc *[]Match, gameID int) error {
+	if err := handleKill(timestamp, payload, slc, gameID); err != nil {
+		return err
+	}
+	if by := strings.LastIndex(payload, " by "); by != -1 {
+		events := (*slc)[gameID].Events
+		events[len(events)-1].Weapon = payload[by+len(" by "):]
+	}
+	return nil
+}
+
+// handleHit parses a payload like "2 3 1 19: Mocinha hit Isgalamido in the
+// Torso", with the victim first and then the attacker, the body part and
+// the weapon.
+func handleHit(timestamp time.Duration, payload string, slc *[]Match, gameID int) error {
+	if len((*slc)) == 0 {
+		return errors.New("Hit line but no match is active")
+	}
+	ids, ok := splitIDs(payload, 4)
+	if !ok {
+		return errors.New("Error on Parse Line")
+	}
+	(*slc)[gameID].Hits = append((*slc)[gameID].Hits, Hit{
+		AttackerID: ids[1],
+		VictimID:   ids[0],
+		Location:   ids[2],
+		Weapon:     ids[3],
+		Time:       timestamp,
+	})
+	return nil
+}
+
+// handleAssist parses a payload like "4 3 2: Dono assisted Mocinha to kill
+// Isgalamido", with the assistant, the killer and the victim.
+func handleAssist(timestamp time.Duration, payload string, slc *[]Match, gameID int) error {
+	if len((*slc)) == 0 {
+		return errors.New("Assist line but no match is active")
+	}
+	ids, ok := splitIDs(payload, 3)
+	if !ok {
+		return errors.New("Error on Parse Line")
+	}
+	(*slc)[gameID].Assists = append((*slc)[gameID].Assists, Assist{
+		AssistantID: ids[0],
+		KillerID:    ids[1],
+		VictimID:    ids[2],
+		Time:        timestamp,
+	})
+	return nil
+}
+
+// splitIDs reads the n numbers, separated by spaces, before the colon of a
+// payload like "4 3 2: Dono assisted Mocinha to kill Isgalamido".
+func splitIDs(payload string, n int) ([]int, bool) {
+	colon := strings.IndexByte(payload, ':')
+	if colon == -1 {
+		return nil, false
+	}
+	fields := strings.Fields(payload[:colon])
+	if len(fields) != n {
+		return nil, false
+	}
+	ids := make([]int, n)
+	for i, field := range fields {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		ids[i] = id
+	}
+	return ids, true
+}
+
+// qlPlayerStats is the DATA of a PLAYER_STATS event of Quake Live.
+type qlPlayerStats struct {
+	Name    string   `json:"NAME"`
+	SteamID string   `json:"STEAM_ID"`
+	Score   qlNumber `json:"SCORE"`
+	Kills   qlNumber `json:"KILLS"`
+	Deaths  qlNumber `json:"DEATHS"`
+	Damage  struct {
+		Dealt qlNumber `json:"DEALT"`
+		Taken qlNumber `json:"TAKEN"`
+	} `json:"DAMAGE"`
+	PlayTime qlNumber `json:"PLAY_TIME"`
+}
+
+// qlMatchReport is the DATA of a MATCH_REPORT event of Quake Live.
+type qlMatchReport struct {
+	ExitMessage string   `json:"EXIT_MSG"`
+	Length      qlNumber `json:"GAME_LENGTH"`
+	Aborted     qlNumber `json:"ABORTED"`
+	RedScore    qlNumber `json:"TSCORE0"`
+	BlueScore   qlNumber `json:"TSCORE1"`
+}
+
+// qlNumber is a number of the stats of Quake Live, which some servers log
+// as a string or, for flags, as a boolean.
+type qlNumber int
+
+func (n *qlNumber) UnmarshalJSON(b []byte) error {
+	text := strings.Trim(string(b), `"`)
+	switch text {
+	case "", "null", "false":
+		*n = 0
+		return nil
+	case "true":
+		*n = 1
+		return nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return err
+	}
+	*n = qlNumber(value)
+	return nil
+}
+
+// decodeStats decodes the JSON of a Quake Live stats event into data. The
+// payload is either the DATA of the event or the whole event, like
+// {"TYPE": "PLAYER_STATS", "DATA": {...}}, and text after the JSON is
+// ignored.
+func decodeStats(payload string, data interface{}) error {
+	var event struct {
+		Data json.RawMessage `json:"DATA"`
+	}
+	raw := json.RawMessage{}
+	if err := json.NewDecoder(strings.NewReader(payload)).Decode(&raw); err != nil {
+		return errors.New("Error on Parse Line")
+	}
+	if err := json.Unmarshal(raw, &event); err == nil && len(event.Data) > 0 {
+		raw = event.Data
+	}
+	if err := json.Unmarshal(raw, data); err != nil {
+		return errors.New("Error on Parse Line")
+	}
+	return nil
+}
+
+// handlePlayerStats keeps the stats of a player from a payload like
+// {"NAME": "Isgalamido", "KILLS": 10, "DEATHS": 3, "DAMAGE": {"DEALT":
+// 1200, "TAKEN": 900}, ...}, finding the player by their name.
+func handlePlayerStats(timestamp time.Duration, payload string, match *Match) error {
+	var data qlPlayerStats
+	if err := decodeStats(payload, &data); err != nil {
+		return err
+	}
+	stats := PlayerStats{
+		PlayerID:    -1,
+		Name:        data.Name,
+		SteamID:     data.SteamID,
+		Score:       int(data.Score),
+		Kills:       int(data.Kills),
+		Deaths:      int(data.Deaths),
+		DamageDealt: int(data.Damage.Dealt),
+		DamageTaken: int(data.Damage.Taken),
+		PlayTime:    time.Duration(data.PlayTime) * time.Second,
+		Time:        timestamp,
+	}
+	for index := len(match.Players) - 1; index >= 0; index-- {
+		if match.Players[index].Name == data.Name {
+			stats.PlayerID = match.Players[index].ID
+			break
+		}
+	}
+	match.PlayerStats = append(match.PlayerStats, stats)
+	return nil
+}
+
+// handleMatchReport keeps how the match ended from a payload like
+// {"EXIT_MSG": "Fraglimit hit.", "GAME_LENGTH": 600, "ABORTED": false, ...}.
+func handleMatchReport(timestamp time.Duration, payload string, match *Match) error {
+	var data qlMatchReport
+	if err := decodeStats(payload, &data); err != nil {
+		return err
+	}
+	match.MatchStats = &MatchStats{
+		ExitMessage: data.ExitMessage,
+		Length:      time.Duration(data.Length) * time.Second,
+		Aborted:     data.Aborted != 0,
+		RedScore:    int(data.RedScore),
+		BlueScore:   int(data.BlueScore),
+	}
+	return nil
+}
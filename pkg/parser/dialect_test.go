@@ -0,0 +1,274 @@
+package parser_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var _urtLog = []string{
+	`  0:00 InitGame: \sv_hostname\UrT Server\g_gametype\4\mapname\ut4_turnpike\gamename\q3urt43\version\ioq3 1.35 urt 4.3.4 linux-amd64`,
+	"  0:01 ClientConnect: 0",
+	`  0:01 ClientUserinfoChanged: 0 n\|ABC|Isgalamido\t\1\r\0\tl\0\f0\\f1\\f2\\a0\0\a1\0\a2\0`,
+	"  0:02 ClientConnect: 1",
+	`  0:02 ClientUserinfoChanged: 1 n\Mocinha\t\2\r\1\tl\0\f0\\f1\\f2\\a0\0\a1\0\a2\0`,
+	"  0:03 ClientConnect: 2",
+	`  0:03 ClientUserinfoChanged: 2 n\Dono.da.Bola\t\2\r\0\tl\0\f0\\f1\\f2\\a0\0\a1\0\a2\0`,
+	"  0:10 Hit: 1 0 2 19: |ABC|Isgalamido hit Mocinha in the Torso",
+	"  0:11 Hit: 0 2 1 14: Dono.da.Bola hit |ABC|Isgalamido in the Helmet",
+	"  0:12 Kill: 1 0 19: Mocinha killed |ABC|Isgalamido by UT_MOD_LR300",
+	"  0:12 Assist: 2 1 0: Dono.da.Bola assisted Mocinha to kill |ABC|Isgalamido",
+	"  0:20 Kill: 1022 2 31: <world> killed Dono.da.Bola by UT_MOD_FALLING",
+	"  0:30 ShutdownGame:",
+}
+
+var _urtMatch = parser.Match{
+	Players: []parser.Player{
+		{ID: 0, Name: "|ABC|Isgalamido", Connected: time.Second},
+		{ID: 1, Name: "Mocinha", Connected: 2 * time.Second},
+		{ID: 2, Name: "Dono.da.Bola", Connected: 3 * time.Second},
+	},
+	Events: []parser.Kill{
+		{KillerID: 1, VictimID: 0, MeanOfDeath: 19, Weapon: "UT_MOD_LR300", Time: 12 * time.Second},
+		{KillerID: 1022, VictimID: 2, MeanOfDeath: 31, Weapon: "UT_MOD_FALLING", Time: 20 * time.Second},
+	},
+	Hits: []parser.Hit{
+		{AttackerID: 0, VictimID: 1, Location: 2, Weapon: 19, Time: 10 * time.Second},
+		{AttackerID: 2, VictimID: 0, Location: 1, Weapon: 14, Time: 11 * time.Second},
+	},
+	Assists: []parser.Assist{
+		{AssistantID: 2, KillerID: 1, VictimID: 0, Time: 12 * time.Second},
+	},
+	StartTime: 0,
+	EndTime:   30 * time.Second,
+	Settings: map[string]string{
+		"sv_hostname": "UrT Server",
+		"g_gametype":  "4",
+		"mapname":     "ut4_turnpike",
+		"gamename":    "q3urt43",
+		"version":     "ioq3 1.35 urt 4.3.4 linux-amd64",
+	},
+}
+
+var _qlLog = []string{
+	`  0:00 InitGame: \sv_hostname\QL Server\mapname\campgrounds\gamename\baseqz\version\QuakeLive  1069 linux-x64 Jan 21 2016 11:42:46`,
+	"  0:01 ClientConnect: 0",
+	`  0:01 ClientUserinfoChanged: 0 n\Isgalamido\t\0`,
+	"  0:02 ClientConnect: 1",
+	`  0:02 ClientUserinfoChanged: 1 n\Mocinha\t\0`,
+	"  0:10 Kill: 0 1 10: Isgalamido killed Mocinha by MOD_RAILGUN",
+	`  9:59 PLAYER_STATS: {"NAME": "Isgalamido", "STEAM_ID": "76561198000000000", "SCORE": 1, "KILLS": 1, "DEATHS": 0, "DAMAGE": {"DEALT": 100, "TAKEN": 0}, "PLAY_TIME": 598}`,
+	`  9:59 PLAYER_STATS: {"TYPE": "PLAYER_STATS", "DATA": {"NAME": "Mocinha", "SCORE": "0", "KILLS": "0", "DEATHS": "1", "DAMAGE": {"DEALT": "0", "TAKEN": "100"}, "PLAY_TIME": "597"}}`,
+	`  9:59 MATCH_REPORT: {"EXIT_MSG": "Timelimit hit.", "GAME_LENGTH": 599, "ABORTED": false, "TSCORE0": 0, "TSCORE1": 0}`,
+	"  10:00 ShutdownGame:",
+}
+
+func TestQuakeLiveStats(t *testing.T) {
+	got := []parser.Match{}
+	stream := parser.Stream{OnMatchEnd: func(index int, match parser.Match) error {
+		got = append(got, match)
+		return nil
+	}}
+	assert.Nil(t, parseWith(&stream, _qlLog))
+	assert.Equal(t, []parser.PlayerStats{
+		{
+			PlayerID:    0,
+			Name:        "Isgalamido",
+			SteamID:     "76561198000000000",
+			Score:       1,
+			Kills:       1,
+			DamageDealt: 100,
+			PlayTime:    598 * time.Second,
+			Time:        599 * time.Second,
+		},
+		{
+			PlayerID:    1,
+			Name:        "Mocinha",
+			Deaths:      1,
+			DamageTaken: 100,
+			PlayTime:    597 * time.Second,
+			Time:        599 * time.Second,
+		},
+	}, got[0].PlayerStats)
+	assert.Equal(t, &parser.MatchStats{ExitMessage: "Timelimit hit.", Length: 599 * time.Second}, got[0].MatchStats)
+
+	stream = parser.Stream{Dialect: parser.QuakeLive}
+	assert.Nil(t, stream.ParseLine(_qlLog[0]))
+	assert.Equal(t, errors.New("Error on Parse Line"), stream.ParseLine("  9:59 PLAYER_STATS: NAME=Isgalamido"))
+}
+
+func TestDetectDialect(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		dialect  parser.Dialect
+	}{
+		{
+			name:     "Quake 3 Arena",
+			settings: map[string]string{"gamename": "baseq3", "version": "ioq3 1.36_GIT_ba68b99c-2018-01-23 win_msvc64 x86_64 Jan 23 2018"},
+			dialect:  parser.Baseq3,
+		},
+		{
+			name:     "Quake 3 Arena without a gamename",
+			settings: map[string]string{"mapname": "q3dm17"},
+			dialect:  parser.Baseq3,
+		},
+		{
+			name:     "OpenArena by its gamename",
+			settings: map[string]string{"gamename": "baseoa"},
+			dialect:  parser.OpenArena,
+		},
+		{
+			name:     "OpenArena by its version",
+			settings: map[string]string{"version": "ioq3+oa 1.35 linux-x86_64 Jan 17 2012"},
+			dialect:  parser.OpenArena,
+		},
+		{
+			name:     "Urban Terror 4.1",
+			settings: map[string]string{"gamename": "q3ut4"},
+			dialect:  parser.UrbanTerror,
+		},
+		{
+			name:     "Urban Terror 4.3",
+			settings: _urtMatch.Settings,
+			dialect:  parser.UrbanTerror,
+		},
+		{
+			name:     "Quake Live",
+			settings: map[string]string{"version": "QuakeLive  1069 linux-x64 Jan 21 2016 11:42:46"},
+			dialect:  parser.QuakeLive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.dialect.Name(), parser.DetectDialect(tt.settings).Name())
+		})
+	}
+}
+
+func TestLookupDialect(t *testing.T) {
+	dialect, err := parser.LookupDialect("URT")
+	assert.Nil(t, err)
+	assert.Equal(t, parser.UrbanTerror, dialect)
+
+	_, err = parser.LookupDialect("quake4")
+	assert.Equal(t, errors.New(`Unknown dialect "quake4", expected one of urt, quakelive, openarena, baseq3`), err)
+}
+
+func TestStreamDialect(t *testing.T) {
+	baseq3Log := []string{
+		`  0:00 InitGame: \mapname\q3dm17\gamename\baseq3`,
+		"  0:01 ClientConnect: 2",
+		`  0:01 ClientUserinfoChanged: 2 n\Isga.lamido\t\0`,
+		"  0:02 ClientConnect: 3",
+		`  0:02 ClientUserinfoChanged: 3 n\Mocinha\t\0`,
+		"  0:10 Kill: 2 3 10: Isga.lamido killed Mocinha by MOD_RAILGUN",
+		"  0:20 ShutdownGame:",
+	}
+	baseq3Match := parser.Match{
+		Players:   []parser.Player{{ID: 2, Name: "Isga", Connected: time.Second}, {ID: 3, Name: "Mocinha", Connected: 2 * time.Second}},
+		Events:    []parser.Kill{{KillerID: 2, VictimID: 3, MeanOfDeath: 10, Time: 10 * time.Second}},
+		StartTime: 0,
+		EndTime:   20 * time.Second,
+		Settings:  map[string]string{"mapname": "q3dm17", "gamename": "baseq3"},
+	}
+	openArenaMatch := baseq3Match
+	openArenaMatch.Players = []parser.Player{{ID: 2, Name: "Isga.lamido", Connected: time.Second}, {ID: 3, Name: "Mocinha", Connected: 2 * time.Second}}
+	openArenaMatch.Events = []parser.Kill{{KillerID: 2, VictimID: 3, MeanOfDeath: 10, Weapon: "MOD_RAILGUN", Time: 10 * time.Second}}
+
+	tests := []struct {
+		name    string
+		lines   []string
+		dialect parser.Dialect
+		matches []parser.Match
+	}{
+		{
+			name:    "Dialect of each match",
+			lines:   append(append([]string{}, _urtLog...), baseq3Log...),
+			matches: []parser.Match{_urtMatch, baseq3Match},
+		},
+		{
+			name:    "Every match with the dialect chosen",
+			lines:   baseq3Log,
+			dialect: parser.OpenArena,
+			matches: []parser.Match{openArenaMatch},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, workers := range []int{0, 2} {
+				got := []parser.Match{}
+				onMatchEnd := func(index int, match parser.Match) error {
+					got = append(got, parser.WithoutIndexes([]parser.Match{match})[0])
+					return nil
+				}
+				var p interface {
+					ParseLine(string) error
+					Close() error
+				} = &parser.Stream{Dialect: tt.dialect, OnMatchEnd: onMatchEnd}
+				if workers > 0 {
+					p = &parser.Parallel{Workers: workers, Dialect: tt.dialect, OnMatchEnd: onMatchEnd}
+				}
+				assert.Nil(t, parseWith(p, tt.lines))
+				assert.Equal(t, tt.matches, got)
+			}
+		})
+	}
+}
+
+func TestStreamDialectRestore(t *testing.T) {
+	first := parser.Stream{}
+	for _, line := range _urtLog[:5] {
+		assert.Nil(t, first.ParseLine(line))
+	}
+	state := first.State()
+
+	got := []parser.Match{}
+	second := parser.Stream{OnMatchEnd: func(index int, match parser.Match) error {
+		got = append(got, match)
+		return nil
+	}}
+	second.Restore(state)
+	assert.Nil(t, parseWith(&second, []string{"  0:12 Kill: 1 0 19: Mocinha killed |ABC|Isgalamido by UT_MOD_LR300"}))
+	assert.Equal(t, []parser.Kill{{KillerID: 1, VictimID: 0, MeanOfDeath: 19, Weapon: "UT_MOD_LR300", Time: 12 * time.Second}}, got[0].Events)
+}
+
+func TestUrbanTerrorErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		err  error
+	}{
+
+		{
+			name: "Hit without the weapon",
+			line: "  0:10 Hit: 1 0 2: |ABC|Isgalamido hit Mocinha in the Torso",
+			err:  errors.New("Error on Parse Line"),
+		},
+		{
+			name: "Assist with a name for an id",
+			line: "  0:12 Assist: Dono 1 0: Dono.da.Bola assisted Mocinha to kill |ABC|Isgalamido",
+			err:  errors.New("Error on Parse Line"),
+		},
+		{
+			name: "Empty name",
+			line: `  0:01 ClientUserinfoChanged: 0 n\\t\1`,
+			err:  errors.New("Error on Parse Line"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := parser.Stream{Dialect: parser.UrbanTerror}
+			for _, line := range _urtLog[:3] {
+				assert.Nil(t, stream.ParseLine(line))
+			}
+			assert.Equal(t, tt.err, stream.ParseLine(tt.line))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package parser
+
+// WithoutIndexes returns copies of matches without their index of slots,
+// which is an internal cache, so they can be compared to literals.
+func WithoutIndexes(matches []Match) []Match {
+	copies := []Match{}
+	for _, match := range matches {
+		match.slots = nil
+		copies = append(copies, match)
+	}
+	return copies
+}
+
+// NewDialects returns copies of Dialects with registries of their own,
+// Baseq3 using base, so tests can register handlers without changing the
+// global ones.
+func NewDialects(base *Registry) []Dialect {
+	registries := map[Dialect]*Registry{
+		UrbanTerror: newUrbanTerrorRegistry(),
+		QuakeLive:   newQuakeLiveRegistry(),
+		OpenArena:   newNamedRegistry(),
+		Baseq3:      base,
+	}
+	dialects := []Dialect{}
+	for _, d := range Dialects {
+		known := d.(*dialect)
+		dialects = append(dialects, &dialect{name: known.name, detect: known.detect, registry: registries[d]})
+	}
+	return dialects
+}
+
+// RegisterIn sets the handler of an event like Register, but in base and
+// in the registries of dialects instead of the global ones.
+func RegisterIn(base *Registry, dialects []Dialect, event string, handler Handler) {
+	register(base, dialects, event, handler)
+}
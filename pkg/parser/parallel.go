@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Parallel parses a log like Stream does, but splits it at the InitGame
+// lines and parses each match in a pool of goroutines. Matches are still
+// handed to OnMatchEnd in the order they were played, and always from the
+// goroutine calling ParseLine or Close, so the output is the same of a
+// Stream. Parsing stops at the first error, after the matches before the
+// line that caused it were handed to OnMatchEnd, and the workers are
+// stopped before ParseLine or Close returns it.
+type Parallel struct {
+	// Workers is how many matches are parsed at the same time. If not
+	// set, it's the number of CPUs.
+	Workers int
+	// OnMatchEnd receives the 1-based index of the match in the log and
+	// the match itself. If it returns an error, parsing stops with it.
+	OnMatchEnd func(index int, match Match) error
+	// Registry and Dialect are set on the Stream that parses each match.
+	// The handlers are called by the workers, so the ones that share state
+	// must synchronize it.
+	Registry *Registry
+	Dialect  Dialect
+
+	segment []string
+	start   int
+	inits   int
+	seq     int
+	next    int
+	pending map[int]segmentResult
+	jobs    chan segmentJob
+	results chan segmentResult
+	running sync.WaitGroup
+	err     error
+}
+
+type segmentJob struct {
+	seq   int
+	count int
+	lines []string
+}
+
+type segmentResult struct {
+	seq     int
+	indexes []int
+	matches []Match
+	err     error
+}
+
+// ParseLine adds a line of the log to the match it belongs to, which is
+// parsed once the next InitGame line is seen.
+func (p *Parallel) ParseLine(line string) error {
+	if p.err != nil {
+		return p.err
+	}
+	if strings.Contains(line, "InitGame") {
+		if _, event, _, ok := Tokenize(line); ok && event == "InitGame" {
+			p.dispatch()
+			p.start = p.inits
+			p.inits++
+		}
+	}
+	p.segment = append(p.segment, line)
+	p.collect(false)
+	if p.err != nil {
+		p.stop()
+	}
+	return p.err
+}
+
+// Close parses what is left of the log and waits for every match to be
+// handed to OnMatchEnd.
+func (p *Parallel) Close() error {
+	if p.err == nil {
+		p.dispatch()
+	}
+	for p.err == nil && p.next < p.seq {
+		p.collect(true)
+	}
+	p.stop()
+	p.start = p.inits
+	return p.err
+}
+
+// stop discards the jobs no worker took yet and waits for the workers to
+// finish the ones they are parsing and return.
+func (p *Parallel) stop() {
+	if p.jobs == nil {
+		return
+	}
+	for discarded := true; discarded; {
+		select {
+		case <-p.jobs:
+		default:
+			discarded = false
+		}
+	}
+	close(p.jobs)
+	p.jobs = nil
+	p.running.Wait()
+}
+
+func (p *Parallel) workers() int {
+	if p.Workers > 0 {
+		return p.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// dispatch sends the lines read so far to a worker, waiting for the
+// results of older ones when too many are being parsed.
+func (p *Parallel) dispatch() {
+	if len(p.segment) == 0 {
+		return
+	}
+	if p.jobs == nil {
+		limit := 2 * p.workers()
+		p.jobs = make(chan segmentJob, limit)
+		p.results = make(chan segmentResult, limit)
+		p.pending = map[int]segmentResult{}
+		registry, dialect, jobs, results := p.Registry, p.Dialect, p.jobs, p.results
+		for i := 0; i < p.workers(); i++ {
+			p.running.Add(1)
+			go func() {
+				defer p.running.Done()
+				parseSegments(registry, dialect, jobs, results)
+			}()
+		}
+	}
+	for p.err == nil && p.seq-p.next >= cap(p.jobs) {
+		p.collect(true)
+	}
+	if p.err != nil {
+		return
+	}
+	p.jobs <- segmentJob{seq: p.seq, count: p.start, lines: p.segment}
+	p.seq++
+	p.segment = nil
+}
+
+// collect receives the results of the workers, waiting for one if block
+// is set, and hands the matches that are next in order to OnMatchEnd.
+func (p *Parallel) collect(block bool) {
+	if p.results == nil {
+		return
+	}
+	for {
+		var result segmentResult
+		if block {
+			result = <-p.results
+			block = false
+		} else {
+			select {
+			case result = <-p.results:
+			default:
+				return
+			}
+		}
+		p.pending[result.seq] = result
+		for {
+			ready, ok := p.pending[p.next]
+			if !ok {
+				break
+			}
+			delete(p.pending, p.next)
+			p.next++
+			for i, match := range ready.matches {
+				if p.err == nil && p.OnMatchEnd != nil {
+					p.err = p.OnMatchEnd(ready.indexes[i], match)
+				}
+			}
+			if p.err == nil {
+				p.err = ready.err
+			}
+		}
+	}
+}
+
+// parseSegments parses each job with a Stream that continues from the
+// matches started before it.
+func parseSegments(registry *Registry, dialect Dialect, jobs <-chan segmentJob, results chan<- segmentResult) {
+	for job := range jobs {
+		result := segmentResult{seq: job.seq}
+		stream := Stream{
+			Registry: registry,
+			Dialect:  dialect,
+			OnMatchEnd: func(index int, match Match) error {
+				result.indexes = append(result.indexes, index)
+				result.matches = append(result.matches, match)
+				return nil
+			},
+		}
+		stream.Restore(StreamState{Count: job.count})
+		for _, line := range job.lines {
+			if result.err = stream.ParseLine(line); result.err != nil {
+				break
+			}
+		}
+		if result.err == nil {
+			result.err = stream.Close()
+		}
+		results <- result
+	}
+}
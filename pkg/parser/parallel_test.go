@@ -0,0 +1,154 @@
+package parser_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+type indexedMatch struct {
+	Index int
+	Match parser.Match
+}
+
+// generateLog writes a log of n matches with a few players and kills each,
+// some of them ended by ShutdownGame and others by the next InitGame.
+func generateLog(n int) []string {
+	lines := []string{"  0:00 ------------------------------------------------------------"}
+	for i := 0; i < n; i++ {
+		lines = append(lines,
+			fmt.Sprintf(`%3d:00 InitGame: \mapname\q3dm%d\sv_hostname\Server %d`, i, i%7, i),
+			fmt.Sprintf("%3d:01 ClientConnect: 2", i),
+			fmt.Sprintf(`%3d:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0`, i),
+			fmt.Sprintf("%3d:02 ClientConnect: 3", i),
+			fmt.Sprintf(`%3d:02 ClientUserinfoChanged: 3 n\Mocinha\t\0`, i),
+		)
+		for k := 0; k < i%5; k++ {
+			lines = append(lines, fmt.Sprintf("%3d:%02d Kill: %d 3 %d: Isgalamido killed Mocinha", i, 10+k, 2+k%2*1020, k+1))
+		}
+		lines = append(lines, fmt.Sprintf("%3d:30 say: Mocinha: gg InitGame: not really", i))
+		if i%3 != 0 {
+			lines = append(lines,
+				fmt.Sprintf("%3d:40 ShutdownGame:", i),
+				fmt.Sprintf("%3d:40 ------------------------------------------------------------", i),
+				fmt.Sprintf("%3d:41 ClientConnect: 4", i),
+			)
+		}
+	}
+	return lines
+}
+
+// parseWith parses lines with p up to the first error, always closing p.
+func parseWith(p interface {
+	ParseLine(string) error
+	Close() error
+}, lines []string) (err error) {
+	defer func() {
+		if closeErr := p.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	for _, line := range lines {
+		if err = p.ParseLine(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestParallel(t *testing.T) {
+	valid := generateLog(50)
+	broken := append(append([]string{}, valid[:120]...), " 99:00 Kill: 7 3 1: Nobody killed Mocinha")
+	broken = append(broken, valid[120:]...)
+	beforeInit := append([]string{" 0:00 ClientConnect: 2"}, valid...)
+
+	tests := []struct {
+		name    string
+		lines   []string
+		workers int
+		fails   bool
+	}{
+		{name: "Same matches of a Stream with one worker", lines: valid, workers: 1},
+		{name: "Same matches of a Stream with many workers", lines: valid, workers: 4},
+		{name: "Same matches of a Stream with as many workers as CPUs", lines: valid},
+		{name: "Same matches and error of a Stream", lines: broken, workers: 3, fails: true},
+		{name: "Same error of a Stream before the first match", lines: beforeInit, workers: 2, fails: true},
+		{name: "No lines", lines: []string{}, workers: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The match a Stream fails on is handed to OnMatchEnd by Close,
+			// while Parallel stops before it, so the Stream isn't closed.
+			want := []indexedMatch{}
+			stream := parser.Stream{OnMatchEnd: func(index int, match parser.Match) error {
+				want = append(want, indexedMatch{index, match})
+				return nil
+			}}
+			var wantErr error
+			for _, line := range tt.lines {
+				if wantErr = stream.ParseLine(line); wantErr != nil {
+					break
+				}
+			}
+			if wantErr == nil {
+				wantErr = stream.Close()
+			}
+
+			got := []indexedMatch{}
+			parallel := parser.Parallel{Workers: tt.workers, OnMatchEnd: func(index int, match parser.Match) error {
+				got = append(got, indexedMatch{index, match})
+				return nil
+			}}
+			err := parseWith(&parallel, tt.lines)
+
+			assert.Equal(t, tt.fails, err != nil)
+			assert.Equal(t, wantErr, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestParallelOnMatchEndError(t *testing.T) {
+	calls := 0
+	parallel := parser.Parallel{Workers: 2, OnMatchEnd: func(index int, match parser.Match) error {
+		calls++
+		if index == 10 {
+			return errors.New("Stop")
+		}
+		return nil
+	}}
+	err := parseWith(&parallel, generateLog(50))
+	assert.Equal(t, errors.New("Stop"), err)
+	assert.Equal(t, 10, calls)
+}
+
+func TestParallelStopsWorkers(t *testing.T) {
+	registry := parser.NewRegistry()
+	initGame, _ := registry.Handler("InitGame")
+	parsed := 0
+	registry.Register("InitGame", func(timestamp time.Duration, payload string, matches *[]parser.Match, current int) error {
+		parsed++
+		return initGame(timestamp, payload, matches, current)
+	})
+	parallel := parser.Parallel{Workers: 1, Registry: registry, OnMatchEnd: func(index int, match parser.Match) error {
+		return errors.New("Stop")
+	}}
+	lines := generateLog(50)
+	var err error
+	for _, line := range lines {
+		if err = parallel.ParseLine(line); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, errors.New("Stop"), err)
+
+	// The worker returned with ParseLine, so parsed isn't changed anymore,
+	// and the matches left in its jobs were never parsed.
+	assert.Less(t, parsed, 10)
+	assert.Equal(t, errors.New("Stop"), parallel.Close())
+}
@@ -2,94 +2,167 @@ package parser
 
 import (
 	"errors"
-	"regexp"
+	"net"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const _worldID int = 1022
 
+// ErrUnknownKiller is the error of a Kill line by a client slot no player
+// connected to in the match.
+var ErrUnknownKiller = errors.New("Kill by a non existent player")
+
 // ParseLine will receive a game id, a slice of matches and a string
 // of a line from log file of Quake 3 Arena Server and then parse
 // this line and add it to the Matches slice where appropriated.
+// Events are handled by the handlers of DefaultRegistry.
 func ParseLine(gameID int, slc *[]Match, line string) error {
-	if line == "" {
-		return errors.New("Error on Parse Line")
+	return DefaultRegistry.ParseLine(gameID, slc, line)
+}
+
+func handleInitGame(timestamp time.Duration, payload string, slc *[]Match, gameID int) error {
+	*slc = append((*slc), Match{
+		Players:   []Player{},
+		Events:    []Kill{},
+		StartTime: timestamp,
+		Settings:  ParseInfoString(payload),
+	})
+	return nil
+}
+
+func handleClientConnect(timestamp time.Duration, payload string, slc *[]Match, gameID int) error {
+	if len((*slc)) == 0 {
+		return errors.New("ClientConnect line without an initialized match")
 	}
-	r, err := regexp.Compile(`\d+:\d+ (\w+): (.*)`)
+	playerID, _ := strconv.Atoi(payload)
+	match := &(*slc)[gameID]
+	match.Players = append(match.Players, Player{
+		ID:        playerID,
+		Name:      "",
+		Connected: timestamp,
+	})
+	match.setSlot(playerID, len(match.Players)-1)
+	return nil
+}
+
+// handleClientDisconnect keeps when the player of a slot left the match.
+// Slots no one connected to in the match are ignored.
+func handleClientDisconnect(timestamp time.Duration, payload string, match *Match) error {
+	playerID, err := strconv.Atoi(strings.TrimSpace(payload))
 	if err != nil {
 		return errors.New("Error on Parse Line")
 	}
-	matchs := r.FindStringSubmatch(line)
-	switch matchs[1] {
-	case "InitGame":
-		*slc = append((*slc), Match{
-			Players: []Player{},
-			Events:  []Kill{},
-		})
-	case "ClientConnect":
-		if len((*slc)) == 0 {
-			return errors.New("ClientConnect line without an initialized match")
-		}
-		playerID, _ := strconv.Atoi(matchs[2])
-		(*slc)[gameID].Players = append((*slc)[gameID].Players, Player{
-			ID:   playerID,
-			Name: "",
-		})
-	case "ClientUserinfoChanged":
-		if len((*slc)) == 0 {
-			return errors.New("Updating player with no matches running")
-		}
-		if len((*slc)[gameID].Players) == 0 {
-			return errors.New("Updating player with no players on match")
-		}
-		re, err := regexp.Compile(`^(\d+) n\\(\w+).*$`)
-		if err != nil {
-			return errors.New("Error on Parse Line")
-		}
-		pInfos := re.FindStringSubmatch(matchs[2])
-		userID, _ := strconv.Atoi(pInfos[1])
-		userIndex := FindUserByID((*slc)[gameID].Players, userID)
-		if userIndex == -1 {
-			return errors.New("Trying to update a user that doesn't exists")
-		}
-		(*slc)[gameID].Players[userIndex].Name = pInfos[2]
-	case "Kill":
-		if len((*slc)) == 0 {
-			return errors.New("Kill attempt but no match is active")
-		}
-		if len((*slc)[gameID].Players) == 0 {
-			return errors.New("Kill attempt but no one is on the match")
-		}
-		re, err := regexp.Compile(`^(\d+) (\d+) (\d+): .*$`)
-		if err != nil {
-			return errors.New("Error on Parse Line")
-		}
-		pInfos := re.FindStringSubmatch(matchs[2])
-		killerID, _ := strconv.Atoi(pInfos[1])
-		victimID, _ := strconv.Atoi(pInfos[2])
-		meanOfDeath, _ := strconv.Atoi(pInfos[3])
-		killerIndex := FindUserByID((*slc)[gameID].Players, killerID)
-		if killerIndex == -1 && killerID != _worldID {
-			return errors.New("Kill by a non existent player")
-		}
-		victimIndex := FindUserByID((*slc)[gameID].Players, victimID)
-		if victimIndex == -1 {
-			return errors.New("Kill attempt to a non existent player")
-		}
-		(*slc)[gameID].Events = append((*slc)[gameID].Events, Kill{
-			KillerID:    killerID,
-			VictimID:    victimID,
-			MeanOfDeath: meanOfDeath,
-		})
-	default:
+	if index := match.PlayerIndex(playerID); index != -1 {
+		match.Players[index].Disconnected = timestamp
+	}
+	return nil
+}
+
+func handleClientUserinfoChanged(timestamp time.Duration, payload string, slc *[]Match, gameID int) error {
+	if len((*slc)) == 0 {
+		return errors.New("Updating player with no matches running")
+	}
+	if len((*slc)[gameID].Players) == 0 {
+		return errors.New("Updating player with no players on match")
+	}
+	id, name, info, ok := splitUserinfoChanged(payload)
+	if !ok {
+		return errors.New("Error on Parse Line")
+	}
+	userID, _ := strconv.Atoi(id)
+	userIndex := (*slc)[gameID].PlayerIndex(userID)
+	if userIndex == -1 {
+		return errors.New("Trying to update a user that doesn't exists")
+	}
+	(*slc)[gameID].Players[userIndex].Name = name
+	updateUserinfo(&(*slc)[gameID].Players[userIndex], ParseInfoString(info))
+	return nil
+}
+
+func handleClientUserinfo(timestamp time.Duration, payload string, slc *[]Match, gameID int) error {
+	if len((*slc)) == 0 {
+		return errors.New("Updating player with no matches running")
+	}
+	fields := strings.SplitN(payload, " ", 2)
+	userID, err := strconv.Atoi(fields[0])
+	if err != nil || len(fields) < 2 {
+		return errors.New("Error on Parse Line")
+	}
+	// Mods that log the userinfo may do it before ClientConnect.
+	if userIndex := (*slc)[gameID].PlayerIndex(userID); userIndex != -1 {
+		updateUserinfo(&(*slc)[gameID].Players[userIndex], ParseInfoString(fields[1]))
+	}
+	return nil
+}
+
+func handleKill(timestamp time.Duration, payload string, slc *[]Match, gameID int) error {
+	if len((*slc)) == 0 {
+		return errors.New("Kill attempt but no match is active")
+	}
+	if len((*slc)[gameID].Players) == 0 {
+		return errors.New("Kill attempt but no one is on the match")
+	}
+	killer, victim, mean, ok := splitKill(payload)
+	if !ok {
+		return errors.New("Error on Parse Line")
+	}
+	killerID, _ := strconv.Atoi(killer)
+	victimID, _ := strconv.Atoi(victim)
+	meanOfDeath, _ := strconv.Atoi(mean)
+	killerIndex := (*slc)[gameID].PlayerIndex(killerID)
+	if killerIndex == -1 && killerID != _worldID {
+		return ErrUnknownKiller
+	}
+	victimIndex := (*slc)[gameID].PlayerIndex(victimID)
+	if victimIndex == -1 {
+		return errors.New("Kill attempt to a non existent player")
+	}
+	(*slc)[gameID].Events = append((*slc)[gameID].Events, Kill{
+		KillerID:    killerID,
+		VictimID:    victimID,
+		MeanOfDeath: meanOfDeath,
+		Time:        timestamp,
+	})
+	return nil
+}
+
+// handleItem keeps an item picked up by a player, from a payload like
+// "2 weapon_rocketlauncher". Items of other shapes, logged by some mods,
+// are ignored.
+func handleItem(timestamp time.Duration, payload string, match *Match) error {
+	fields := strings.Fields(payload)
+	if len(fields) != 2 {
 		return nil
 	}
+	playerID, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil
+	}
+	match.Items = append(match.Items, Item{PlayerID: playerID, Name: fields[1], Time: timestamp})
 	return nil
 }
 
+// handleSay keeps a chat message, from a payload like "Isgalamido: gg".
+// Messages are logged with the name of the player instead of their slot,
+// and without a name when said by the server console.
+func handleSay(team bool) func(timestamp time.Duration, payload string, match *Match) error {
+	return func(timestamp time.Duration, payload string, match *Match) error {
+		message := Message{Text: payload, Team: team, Time: timestamp}
+		if colon := strings.Index(payload, ": "); colon != -1 {
+			message.Name, message.Text = payload[:colon], payload[colon+2:]
+		}
+		match.Chat = append(match.Chat, message)
+		return nil
+	}
+}
+
 // FindUserByID FindUserById receives a slice of Players and a
 // Quake 3 Arena Server user ID and return the index in the slice
 // for that specific player.
+// It scans players and finds the first one to use a slot, while
+// Match.PlayerIndex finds the last one, using the index of the match.
 func FindUserByID(players []Player, id int) int {
 	index := -1
 	for key, value := range players {
@@ -101,10 +174,73 @@ func FindUserByID(players []Player, id int) int {
 	return index
 }
 
+// ParseTimestamp converts the "minutes:seconds" prefix of a log line,
+// which counts the server uptime, into a time.Duration.
+func ParseTimestamp(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	colon := strings.IndexByte(value, ':')
+	if colon == -1 {
+		return 0, errors.New("Invalid timestamp")
+	}
+	minutes, err := strconv.Atoi(value[:colon])
+	if err != nil {
+		return 0, errors.New("Invalid timestamp")
+	}
+	seconds, err := strconv.Atoi(value[colon+1:])
+	if err != nil {
+		return 0, errors.New("Invalid timestamp")
+	}
+	return time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, nil
+}
+
+// updateUserinfo copies the keys of a userinfo string that identify the
+// client, cl_guid (or guid) and ip, without the port, into player. Bots are
+// told apart by the skill key, that only they have, or by the "bot" ip.
+func updateUserinfo(player *Player, info map[string]string) {
+	if info["skill"] != "" || info["ip"] == "bot" {
+		player.IsBot = true
+		return
+	}
+	for _, key := range []string{"cl_guid", "guid"} {
+		if value := info[key]; value != "" {
+			player.GUID = value
+			break
+		}
+	}
+	if ip := info["ip"]; ip != "" {
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		player.IP = ip
+	}
+}
+
+// ParseInfoString receives a Quake 3 Arena info string, like the
+// payload of InitGame (\key\value\key\value), and returns it as a map.
+func ParseInfoString(info string) map[string]string {
+	settings := map[string]string{}
+	parts := strings.Split(strings.TrimPrefix(info, "\\"), "\\")
+	for i := 0; i+1 < len(parts); i += 2 {
+		settings[parts[i]] = parts[i+1]
+	}
+	return settings
+}
+
 // Player stores infos from a player of Quake 3 Arena.
 type Player struct {
 	ID   int
 	Name string
+	// GUID and IP identify the client across names, when the server
+	// logs them in the userinfo. Most baseq3 servers don't.
+	GUID string `json:",omitempty"`
+	IP   string `json:",omitempty"`
+	// IsBot is set for players controlled by the server.
+	IsBot bool `json:",omitempty"`
+	// Connected and Disconnected are the server uptime logged when the
+	// player connected to and left the match. Disconnected is zero while
+	// the player is still in it.
+	Connected    time.Duration `json:",omitempty"`
+	Disconnected time.Duration `json:",omitempty"`
 }
 
 // Kill stores info about a kill inside a Quake 3 Arena Server.
@@ -112,10 +248,254 @@ type Kill struct {
 	KillerID    int
 	VictimID    int
 	MeanOfDeath int
+	// Weapon is the name of the mean of death as logged, set by the
+	// dialects that don't number them like baseq3.
+	Weapon string `json:",omitempty"`
+	// Time is the server uptime logged on the Kill line.
+	Time time.Duration `json:",omitempty"`
+}
+
+// Hit is a shot that hurt a player without killing them, logged by
+// Urban Terror.
+type Hit struct {
+	AttackerID int
+	VictimID   int
+	// Location is the part of the body hit, numbered by the game.
+	Location int
+	// Weapon is the id of the weapon, numbered by the game.
+	Weapon int
+	// Time is the server uptime logged on the Hit line.
+	Time time.Duration `json:",omitempty"`
+}
+
+// Assist is a player that helped another one to kill a third, logged by
+// Urban Terror.
+type Assist struct {
+	AssistantID int
+	KillerID    int
+	VictimID    int
+	// Time is the server uptime logged on the Assist line.
+	Time time.Duration `json:",omitempty"`
+}
+
+// PlayerStats are the stats of a player in a match, logged by Quake Live
+// when the player leaves or the match ends.
+type PlayerStats struct {
+	// PlayerID is the client slot of the player with the name of the stats
+	// when they were logged, or -1 if no player of the match had it.
+	PlayerID int
+	Name     string
+	SteamID  string `json:",omitempty"`
+	Score    int
+	Kills    int
+	Deaths   int
+	// DamageDealt and DamageTaken are the hit points of damage the player
+	// did to others and suffered.
+	DamageDealt int
+	DamageTaken int
+	// PlayTime is how long the player played the match.
+	PlayTime time.Duration
+	Time     time.Duration `json:",omitempty"`
+}
+
+// MatchStats is how a match ended, logged by Quake Live.
+type MatchStats struct {
+	// ExitMessage is why the match ended, like "Fraglimit hit.".
+	ExitMessage string `json:",omitempty"`
+	// Length is how long the match was played.
+	Length time.Duration
+	// Aborted is set when the match ended before any of its limits.
+	Aborted bool `json:",omitempty"`
+	// RedScore and BlueScore are the scores of the teams, in team modes.
+	RedScore  int `json:",omitempty"`
+	BlueScore int `json:",omitempty"`
+}
+
+// Item is an item, like a weapon or an armor, picked up by a player.
+type Item struct {
+	PlayerID int
+	// Name is the class of the item, like weapon_rocketlauncher.
+	Name string
+	Time time.Duration `json:",omitempty"`
+}
+
+// Message is a chat message said by a player to everyone or, when Team is
+// set, to their team.
+type Message struct {
+	Name string
+	Text string
+	Team bool          `json:",omitempty"`
+	Time time.Duration `json:",omitempty"`
 }
 
 // Match will store infos about a match on a Quake 3 Arena Server.
 type Match struct {
 	Players []Player
 	Events  []Kill
+	// StartTime is the server uptime logged on the InitGame line.
+	StartTime time.Duration
+	// EndTime is the server uptime logged on the ShutdownGame line, or
+	// zero if the match ended without one.
+	EndTime time.Duration `json:",omitempty"`
+	// Settings holds the server info string sent on InitGame.
+	Settings map[string]string
+	// Items are the items picked up and Chat the messages said, in the
+	// order they were logged.
+	Items []Item    `json:",omitempty"`
+	Chat  []Message `json:",omitempty"`
+	// Hits and Assists are only logged by some dialects, and so are the
+	// PlayerStats and the MatchStats.
+	Hits        []Hit         `json:",omitempty"`
+	Assists     []Assist      `json:",omitempty"`
+	PlayerStats []PlayerStats `json:",omitempty"`
+	MatchStats  *MatchStats   `json:",omitempty"`
+
+	// slots maps a client slot to the index in Players of the last player
+	// that connected to it. It's never changed in place, see setSlot.
+	slots map[int]int
+}
+
+// PlayerIndex returns the index in m.Players of the last player that
+// connected to the client slot id, whether still connected or not, or -1
+// if none did. Matches built by ParseLine keep an index of their slots, so
+// the lookup is O(1). Others, like the ones decoded from JSON, are scanned.
+func (m Match) PlayerIndex(id int) int {
+	if index, ok := m.slots[id]; ok && index < len(m.Players) && m.Players[index].ID == id {
+		return index
+	}
+	for index := len(m.Players) - 1; index >= 0; index-- {
+		if m.Players[index].ID == id {
+			return index
+		}
+	}
+	return -1
+}
+
+// PlayerAt returns the index in m.Players of the player connected to the
+// client slot id at t, the last one to connect to it up to then, or -1 if
+// none did. Unlike PlayerIndex, it tells apart the players that took turns
+// on a slot. When t is zero, like the time of the kills of matches saved
+// before kills had one, it's the last player to connect to the slot.
+func (m Match) PlayerAt(id int, t time.Duration) int {
+	index := m.PlayerIndex(id)
+	if t == 0 || index == -1 || m.Players[index].Connected <= t {
+		return index
+	}
+	for index--; index >= 0; index-- {
+		if player := m.Players[index]; player.ID == id && player.Connected <= t {
+			return index
+		}
+	}
+	return -1
+}
+
+// Killer returns the index in m.Players of the player that made kill, or
+// -1 when it was the world or no player of the match was on its slot.
+func (m Match) Killer(kill Kill) int {
+	if kill.KillerID == _worldID {
+		return -1
+	}
+	return m.PlayerAt(kill.KillerID, kill.Time)
+}
+
+// Victim returns the index in m.Players of the player killed by kill, or
+// -1 when no player of the match was on its slot.
+func (m Match) Victim(kill Kill) int {
+	return m.PlayerAt(kill.VictimID, kill.Time)
+}
+
+// setSlot points the client slot id to the player at index. The index is
+// replaced by an updated copy instead of changed in place, so the copies of
+// the match already handed to OnEvent and OnMatchEnd keep theirs.
+func (m *Match) setSlot(id, index int) {
+	slots := make(map[int]int, len(m.slots)+1)
+	if m.slots == nil {
+		for i, player := range m.Players[:index] {
+			slots[player.ID] = i
+		}
+	}
+	for slot, i := range m.slots {
+		slots[slot] = i
+	}
+	slots[id] = index
+	m.slots = slots
+}
+
+// Copy returns a deep copy of m, which can be read while m is still being
+// parsed, like by another goroutine.
+func (m Match) Copy() Match {
+	m.Players = append(m.Players[:0:0], m.Players...)
+	m.Events = append(m.Events[:0:0], m.Events...)
+	m.Items = append(m.Items[:0:0], m.Items...)
+	m.Chat = append(m.Chat[:0:0], m.Chat...)
+	m.Hits = append(m.Hits[:0:0], m.Hits...)
+	m.Assists = append(m.Assists[:0:0], m.Assists...)
+	m.PlayerStats = append(m.PlayerStats[:0:0], m.PlayerStats...)
+	if m.MatchStats != nil {
+		stats := *m.MatchStats
+		m.MatchStats = &stats
+	}
+	if m.Settings != nil {
+		settings := make(map[string]string, len(m.Settings))
+		for key, value := range m.Settings {
+			settings[key] = value
+		}
+		m.Settings = settings
+	}
+	// The copy builds its own index of the slots when it needs one.
+	m.slots = nil
+	return m
+}
+
+// MapName returns the map the match was played on, or an
+// empty string when the InitGame line didn't inform it.
+func (m Match) MapName() string {
+	return m.Settings["mapname"]
+}
+
+// WithoutBots returns a copy of m without its bots and the kills, hits and
+// assists they made or suffered, so only kills between humans, or by the
+// world, are left, and without the stats of the bots.
+func (m Match) WithoutBots() Match {
+	players := []Player{}
+	for _, player := range m.Players {
+		if !player.IsBot {
+			players = append(players, player)
+		}
+	}
+	isBot := func(index int) bool {
+		return index != -1 && m.Players[index].IsBot
+	}
+	events := []Kill{}
+	for _, kill := range m.Events {
+		if !isBot(m.Killer(kill)) && !isBot(m.Victim(kill)) {
+			events = append(events, kill)
+		}
+	}
+	var hits []Hit
+	for _, hit := range m.Hits {
+		if !isBot(m.PlayerAt(hit.AttackerID, hit.Time)) && !isBot(m.PlayerAt(hit.VictimID, hit.Time)) {
+			hits = append(hits, hit)
+		}
+	}
+	var assists []Assist
+	for _, assist := range m.Assists {
+		if !isBot(m.PlayerAt(assist.AssistantID, assist.Time)) && !isBot(m.PlayerAt(assist.KillerID, assist.Time)) &&
+			!isBot(m.PlayerAt(assist.VictimID, assist.Time)) {
+			assists = append(assists, assist)
+		}
+	}
+	var stats []PlayerStats
+	for _, playerStats := range m.PlayerStats {
+		if !isBot(m.PlayerAt(playerStats.PlayerID, playerStats.Time)) {
+			stats = append(stats, playerStats)
+		}
+	}
+	m.Players = players
+	m.Events = events
+	m.Hits = hits
+	m.Assists = assists
+	m.PlayerStats = stats
+	m.slots = nil
+	return m
 }
@@ -3,6 +3,7 @@ package parser_test
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/reesilva/quake-log/pkg/parser"
 	"github.com/stretchr/testify/assert"
@@ -13,6 +14,29 @@ type Parameters struct {
 	Line   string
 }
 
+var initGameSettings = map[string]string{
+	"sv_floodProtect":   "1",
+	"sv_maxPing":        "0",
+	"sv_minPing":        "0",
+	"sv_maxRate":        "10000",
+	"sv_minRate":        "0",
+	"sv_hostname":       "Code Miner Server",
+	"g_gametype":        "0",
+	"sv_privateClients": "2",
+	"sv_maxclients":     "16",
+	"sv_allowDownload":  "0",
+	"dmflags":           "0",
+	"fraglimit":         "20",
+	"timelimit":         "15",
+	"g_maxGameClients":  "0",
+	"capturelimit":      "8",
+	"version":           "ioq3 1.36 linux-x86_64 Apr 12 2009",
+	"protocol":          "68",
+	"mapname":           "q3dm17",
+	"gamename":          "baseq3",
+	"g_needpass":        "0",
+}
+
 func TestParseLine(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -42,8 +66,10 @@ func TestParseLine(t *testing.T) {
 			name: "Line Init Game",
 			want: []parser.Match{
 				{
-					Players: []parser.Player{},
-					Events:  []parser.Kill{},
+					Players:   []parser.Player{},
+					Events:    []parser.Kill{},
+					StartTime: 0,
+					Settings:  initGameSettings,
 				},
 			},
 			expectError: false,
@@ -70,8 +96,9 @@ func TestParseLine(t *testing.T) {
 				{
 					Players: []parser.Player{
 						{
-							ID:   2,
-							Name: "",
+							ID:        2,
+							Name:      "",
+							Connected: 20*time.Minute + 34*time.Second,
 						},
 					},
 					Events: []parser.Kill{},
@@ -117,8 +144,10 @@ func TestParseLine(t *testing.T) {
 					},
 				},
 				{
-					Players: []parser.Player{},
-					Events:  []parser.Kill{},
+					Players:   []parser.Player{},
+					Events:    []parser.Kill{},
+					StartTime: 0,
+					Settings:  initGameSettings,
 				},
 			},
 			expectError: false,
@@ -183,8 +212,9 @@ func TestParseLine(t *testing.T) {
 				{
 					Players: []parser.Player{
 						{
-							ID:   2,
-							Name: "",
+							ID:        2,
+							Name:      "",
+							Connected: 20*time.Minute + 34*time.Second,
 						},
 					},
 					Events: []parser.Kill{},
@@ -225,6 +255,37 @@ func TestParseLine(t *testing.T) {
 				Line: " 20:34 ClientConnect: 2",
 			},
 		},
+		// Client disconnect
+		{
+			name: "Client disconnect",
+			want: []parser.Match{
+				{
+					Players: []parser.Player{
+						{
+							ID:           2,
+							Name:         "Isgalamido",
+							Disconnected: 20*time.Minute + 40*time.Second,
+						},
+					},
+					Events: []parser.Kill{},
+				},
+			},
+			expectError: false,
+			parameters: Parameters{
+				Matchs: []parser.Match{
+					{
+						Players: []parser.Player{
+							{
+								ID:   2,
+								Name: "Isgalamido",
+							},
+						},
+						Events: []parser.Kill{},
+					},
+				},
+				Line: " 20:40 ClientDisconnect: 2",
+			},
+		},
 		// Client user info changed when has no matches
 		{
 			name:          "Client user info changed when has no matches",
@@ -282,6 +343,162 @@ func TestParseLine(t *testing.T) {
 				Line: ` 20:38 ClientUserinfoChanged: 2 n\Isgalamido\t\0\model\uriel/zael\hmodel\uriel/zael\g_redteam\\g_blueteam\\c1\5\c2\5\hc\100\w\0\l\0\tt\0\tl\0`,
 			},
 		},
+		// Client user info changed with a guid
+		{
+			name: "Client user info changed with a guid",
+			want: []parser.Match{
+				{
+					Players: []parser.Player{
+						{
+							ID:   2,
+							Name: "Isgalamido",
+							GUID: "8A9F03B1C2D4E5F6",
+						},
+					},
+					Events: []parser.Kill{},
+				},
+			},
+			expectError: false,
+			parameters: Parameters{
+				Matchs: []parser.Match{
+					{
+						Players: []parser.Player{
+							{
+								ID:   2,
+								Name: "",
+							},
+						},
+						Events: []parser.Kill{},
+					},
+				},
+				Line: ` 20:38 ClientUserinfoChanged: 2 n\Isgalamido\t\0\model\sarge\cl_guid\8A9F03B1C2D4E5F6\hc\100`,
+			},
+		},
+		// Client user info with ip and guid
+		{
+			name: "Client user info with ip and guid",
+			want: []parser.Match{
+				{
+					Players: []parser.Player{
+						{
+							ID:   2,
+							Name: "Isgalamido",
+							GUID: "8A9F03B1C2D4E5F6",
+							IP:   "10.0.0.7",
+						},
+					},
+					Events: []parser.Kill{},
+				},
+			},
+			expectError: false,
+			parameters: Parameters{
+				Matchs: []parser.Match{
+					{
+						Players: []parser.Player{
+							{
+								ID:   2,
+								Name: "Isgalamido",
+							},
+						},
+						Events: []parser.Kill{},
+					},
+				},
+				Line: ` 20:38 ClientUserinfo: 2 \ip\10.0.0.7:27960\name\Isgalamido\cl_guid\8A9F03B1C2D4E5F6`,
+			},
+		},
+		// Client user info changed of a bot
+		{
+			name: "Client user info changed of a bot",
+			want: []parser.Match{
+				{
+					Players: []parser.Player{
+						{
+							ID:    4,
+							Name:  "Sarge",
+							IsBot: true,
+						},
+					},
+					Events: []parser.Kill{},
+				},
+			},
+			expectError: false,
+			parameters: Parameters{
+				Matchs: []parser.Match{
+					{
+						Players: []parser.Player{
+							{
+								ID:   4,
+								Name: "",
+							},
+						},
+						Events: []parser.Kill{},
+					},
+				},
+				Line: ` 21:15 ClientUserinfoChanged: 4 n\Sarge\t\0\model\sarge\hmodel\sarge\c1\4\c2\5\hc\70\w\0\l\0\skill\ 3.00\tt\0\tl\0`,
+			},
+		},
+		// Client user info before the client connects
+		{
+			name: "Client user info before the client connects",
+			want: []parser.Match{
+				{
+					Players: []parser.Player{},
+					Events:  []parser.Kill{},
+				},
+			},
+			expectError: false,
+			parameters: Parameters{
+				Matchs: []parser.Match{
+					{
+						Players: []parser.Player{},
+						Events:  []parser.Kill{},
+					},
+				},
+				Line: ` 20:38 ClientUserinfo: 2 \ip\10.0.0.7:27960\name\Isgalamido`,
+			},
+		},
+		// Client user info changed with a name that is not a word
+		{
+			name:          "Client user info changed with a name that is not a word",
+			want:          []parser.Match{},
+			expectError:   true,
+			expectedError: "Error on Parse Line",
+			parameters: Parameters{
+				Matchs: []parser.Match{
+					{
+						Players: []parser.Player{
+							{
+								ID:   2,
+								Name: "",
+							},
+						},
+						Events: []parser.Kill{},
+					},
+				},
+				Line: ` 20:38 ClientUserinfoChanged: 2 n\[X]Isgalamido\t\0`,
+			},
+		},
+		// Kill with a malformed payload
+		{
+			name:          "Kill with a malformed payload",
+			want:          []parser.Match{},
+			expectError:   true,
+			expectedError: "Error on Parse Line",
+			parameters: Parameters{
+				Matchs: []parser.Match{
+					{
+						Players: []parser.Player{
+							{
+								ID:   2,
+								Name: "Isgalamido",
+							},
+						},
+						Events: []parser.Kill{},
+					},
+				},
+				Line: ` 22:06 Kill: 2 killed someone`,
+			},
+		},
 		// Client user info changed for a user that doesn't exists
 		{
 			name:          "Client user info changed for a user that doesn't exists",
@@ -491,6 +708,7 @@ func TestParseLine(t *testing.T) {
 							KillerID:    1022,
 							VictimID:    2,
 							MeanOfDeath: 22,
+							Time:        21*time.Minute + 7*time.Second,
 						},
 					},
 				},
@@ -554,6 +772,7 @@ func TestParseLine(t *testing.T) {
 							KillerID:    1022,
 							VictimID:    2,
 							MeanOfDeath: 22,
+							Time:        21*time.Minute + 7*time.Second,
 						},
 					},
 				},
@@ -678,6 +897,7 @@ func TestParseLine(t *testing.T) {
 							KillerID:    2,
 							VictimID:    3,
 							MeanOfDeath: 22,
+							Time:        21*time.Minute + 7*time.Second,
 						},
 					},
 				},
@@ -741,6 +961,7 @@ func TestParseLine(t *testing.T) {
 							KillerID:    3,
 							VictimID:    2,
 							MeanOfDeath: 22,
+							Time:        21*time.Minute + 7*time.Second,
 						},
 					},
 				},
@@ -784,6 +1005,66 @@ func TestParseLine(t *testing.T) {
 				Line: ` 21:07 Kill: 3 2 22: test33 killed test22 by MOD_TRIGGER_HURT`,
 			},
 		},
+		// Item picked up
+		{
+			name: "Item picked up",
+			want: []parser.Match{
+				{
+					Players: []parser.Player{{ID: 2, Name: "Isgalamido"}},
+					Events:  []parser.Kill{},
+					Items:   []parser.Item{{PlayerID: 2, Name: "weapon_rocketlauncher", Time: 20*time.Minute + 40*time.Second}},
+				},
+			},
+			parameters: Parameters{
+				Matchs: []parser.Match{
+					{
+						Players: []parser.Player{{ID: 2, Name: "Isgalamido"}},
+						Events:  []parser.Kill{},
+					},
+				},
+				Line: " 20:40 Item: 2 weapon_rocketlauncher",
+			},
+		},
+		// Chat message
+		{
+			name: "Chat message",
+			want: []parser.Match{
+				{
+					Players: []parser.Player{{ID: 2, Name: "Isgalamido"}},
+					Events:  []parser.Kill{},
+					Chat:    []parser.Message{{Name: "Isgalamido", Text: "gg: wp", Time: 21*time.Minute + 15*time.Second}},
+				},
+			},
+			parameters: Parameters{
+				Matchs: []parser.Match{
+					{
+						Players: []parser.Player{{ID: 2, Name: "Isgalamido"}},
+						Events:  []parser.Kill{},
+					},
+				},
+				Line: " 21:15 say: Isgalamido: gg: wp",
+			},
+		},
+		// Team chat message
+		{
+			name: "Team chat message",
+			want: []parser.Match{
+				{
+					Players: []parser.Player{{ID: 2, Name: "Isgalamido"}},
+					Events:  []parser.Kill{},
+					Chat:    []parser.Message{{Name: "Isgalamido", Text: "rush B", Team: true, Time: 21*time.Minute + 16*time.Second}},
+				},
+			},
+			parameters: Parameters{
+				Matchs: []parser.Match{
+					{
+						Players: []parser.Player{{ID: 2, Name: "Isgalamido"}},
+						Events:  []parser.Kill{},
+					},
+				},
+				Line: " 21:16 sayteam: Isgalamido: rush B",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -794,8 +1075,130 @@ func TestParseLine(t *testing.T) {
 					assert.Equal(t, expected, err)
 				}
 			} else {
-				assert.Equal(t, tt.want, tt.parameters.Matchs)
+				assert.Equal(t, tt.want, parser.WithoutIndexes(tt.parameters.Matchs))
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		want          time.Duration
+		expectError   bool
+		expectedError string
+	}{
+		{
+			name:  "Start of the server",
+			value: "  0:00",
+			want:  0,
+		},
+		{
+			name:  "Minutes and seconds",
+			value: " 20:37",
+			want:  20*time.Minute + 37*time.Second,
+		},
+		{
+			name:  "More than an hour of uptime",
+			value: "981:05",
+			want:  981*time.Minute + 5*time.Second,
+		},
+		{
+			name:          "Invalid timestamp",
+			value:         "20-37",
+			expectError:   true,
+			expectedError: "Invalid timestamp",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseTimestamp(tt.value)
+			if tt.expectError {
+				if assert.Error(t, err) {
+					expected := errors.New(tt.expectedError)
+					assert.Equal(t, expected, err)
+				}
+			} else {
+				assert.Equal(t, tt.want, got)
 			}
 		})
 	}
 }
+
+func TestParseInfoString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  map[string]string
+	}{
+		{
+			name:  "Empty info string",
+			value: "",
+			want:  map[string]string{},
+		},
+		{
+			name:  "Info string with empty values",
+			value: `\mapname\q3dm17\g_redteam\\g_blueteam\`,
+			want: map[string]string{
+				"mapname":    "q3dm17",
+				"g_redteam":  "",
+				"g_blueteam": "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parser.ParseInfoString(tt.value))
+		})
+	}
+}
+
+func TestWithoutBots(t *testing.T) {
+	match := parser.Match{
+		Players: []parser.Player{
+			{ID: 2, Name: "Isgalamido"},
+			{ID: 3, Name: "Mocinha"},
+			{ID: 4, Name: "Sarge", IsBot: true},
+		},
+		Events: []parser.Kill{
+			{KillerID: 2, VictimID: 3, MeanOfDeath: 10},
+			{KillerID: 4, VictimID: 2, MeanOfDeath: 6},
+			{KillerID: 3, VictimID: 4, MeanOfDeath: 7},
+			{KillerID: 1022, VictimID: 4, MeanOfDeath: 22},
+			{KillerID: 1022, VictimID: 3, MeanOfDeath: 22},
+		},
+		Settings: map[string]string{"mapname": "q3dm17"},
+	}
+
+	got := match.WithoutBots()
+	assert.Equal(t, []parser.Player{{ID: 2, Name: "Isgalamido"}, {ID: 3, Name: "Mocinha"}}, got.Players)
+	assert.Equal(t, []parser.Kill{
+		{KillerID: 2, VictimID: 3, MeanOfDeath: 10},
+		{KillerID: 1022, VictimID: 3, MeanOfDeath: 22},
+	}, got.Events)
+	assert.Equal(t, 3, len(match.Players))
+	assert.Equal(t, 5, len(match.Events))
+
+	reused := parser.Match{
+		Players: []parser.Player{
+			{ID: 4, Name: "Sarge", IsBot: true, Connected: time.Second, Disconnected: 10 * time.Second},
+			{ID: 2, Name: "Isgalamido", Connected: time.Second},
+			{ID: 4, Name: "Zeh", Connected: 20 * time.Second},
+		},
+		Events: []parser.Kill{
+			{KillerID: 4, VictimID: 2, MeanOfDeath: 6, Time: 5 * time.Second},
+			{KillerID: 4, VictimID: 2, MeanOfDeath: 6, Time: 30 * time.Second},
+		},
+	}
+	got = reused.WithoutBots()
+	assert.Equal(t, []parser.Kill{{KillerID: 4, VictimID: 2, MeanOfDeath: 6, Time: 30 * time.Second}}, got.Events)
+
+	match.Hits = []parser.Hit{{AttackerID: 2, VictimID: 3}, {AttackerID: 4, VictimID: 2}}
+	match.Assists = []parser.Assist{{AssistantID: 3, KillerID: 2, VictimID: 4}, {AssistantID: 2, KillerID: 1022, VictimID: 3}}
+	match.PlayerStats = []parser.PlayerStats{{PlayerID: 2, Name: "Isgalamido"}, {PlayerID: 4, Name: "Sarge"}, {PlayerID: -1, Name: "Dono"}}
+	got = match.WithoutBots()
+	assert.Equal(t, []parser.Hit{{AttackerID: 2, VictimID: 3}}, got.Hits)
+	assert.Equal(t, []parser.Assist{{AssistantID: 2, KillerID: 1022, VictimID: 3}}, got.Assists)
+	assert.Equal(t, []parser.PlayerStats{{PlayerID: 2, Name: "Isgalamido"}, {PlayerID: -1, Name: "Dono"}}, got.PlayerStats)
+}
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"errors"
+	"time"
+)
+
+// Handler parses the payload of an event line logged at timestamp into
+// matches, the matches parsed so far, where current is the index of the
+// match being played. A Handler for InitGame is expected to append a new
+// match, while the others update the current one.
+type Handler func(timestamp time.Duration, payload string, matches *[]Match, current int) error
+
+// MatchHandler turns a function that only needs the match being played
+// into a Handler. Events logged while no match is being played are
+// ignored, like mods that log events between ShutdownGame and InitGame.
+func MatchHandler(handle func(timestamp time.Duration, payload string, match *Match) error) Handler {
+	return func(timestamp time.Duration, payload string, matches *[]Match, current int) error {
+		if current < 0 || current >= len(*matches) {
+			return nil
+		}
+		return handle(timestamp, payload, &(*matches)[current])
+	}
+}
+
+// Registry maps event keywords, like Kill or a mod's Flag, to the Handler
+// of their lines. Handlers must be registered before parsing starts, since
+// a Registry isn't safe to change while it's being used.
+type Registry struct {
+	handlers map[string]Handler
+}
+
+// DefaultRegistry is the Registry used by ParseLine and the Registry of
+// the Baseq3 dialect.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns a Registry with the handlers of the events the parser
+// knows about: InitGame, ClientConnect, ClientUserinfo,
+// ClientUserinfoChanged, ClientDisconnect, Kill, Item, say and sayteam.
+func NewRegistry() *Registry {
+	r := &Registry{handlers: map[string]Handler{}}
+	r.Register("InitGame", handleInitGame)
+	r.Register("ClientConnect", handleClientConnect)
+	r.Register("ClientUserinfo", handleClientUserinfo)
+	r.Register("ClientUserinfoChanged", handleClientUserinfoChanged)
+	r.Register("ClientDisconnect", MatchHandler(handleClientDisconnect))
+	r.Register("Kill", handleKill)
+	r.Register("Item", MatchHandler(handleItem))
+	r.Register("say", MatchHandler(handleSay(false)))
+	r.Register("sayteam", MatchHandler(handleSay(true)))
+	return r
+}
+
+// Register sets the handler of an event, replacing the one it had, built-in
+// or not. A nil handler makes the event be ignored.
+func (r *Registry) Register(event string, handler Handler) {
+	if handler == nil {
+		delete(r.handlers, event)
+		return
+	}
+	r.handlers[event] = handler
+}
+
+// Register sets the handler of an event in DefaultRegistry and in the
+// registries of the other Dialects, so it's used whatever the dialect of a
+// match, chosen or detected. Like the Register method, it must be called
+// before parsing starts, like in an init function. To handle an event of a
+// single dialect, use the Register method of its Registry instead.
+func Register(event string, handler Handler) {
+	register(DefaultRegistry, Dialects, event, handler)
+}
+
+// register sets the handler of an event in base and in the registries of
+// dialects, once in each even when they share one.
+func register(base *Registry, dialects []Dialect, event string, handler Handler) {
+	base.Register(event, handler)
+	for _, d := range dialects {
+		if r := d.Registry(); r != base {
+			r.Register(event, handler)
+		}
+	}
+}
+
+// Handler returns the handler of an event, if it has one.
+func (r *Registry) Handler(event string) (Handler, bool) {
+	handler, ok := r.handlers[event]
+	return handler, ok
+}
+
+// ParseLine parses a line of the log like the ParseLine function, but with
+// the handlers of r. Lines of events without a handler are ignored.
+func (r *Registry) ParseLine(gameID int, slc *[]Match, line string) error {
+	if line == "" {
+		return errors.New("Error on Parse Line")
+	}
+	value, event, payload, ok := Tokenize(line)
+	if !ok {
+		return errors.New("Error on Parse Line")
+	}
+	timestamp, err := ParseTimestamp(value)
+	if err != nil {
+		return errors.New("Error on Parse Line")
+	}
+	handler, ok := r.handlers[event]
+	if !ok {
+		return nil
+	}
+	return handler(timestamp, payload, slc, gameID)
+}
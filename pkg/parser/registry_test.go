@@ -0,0 +1,126 @@
+package parser_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var _flagLog = []string{
+	`  0:00 InitGame: \mapname\q3ctf1`,
+	"  0:01 ClientConnect: 2",
+	`  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\1`,
+	"  0:02 ClientConnect: 3",
+	`  0:02 ClientUserinfoChanged: 3 n\Mocinha\t\2`,
+	"  1:10 Flag: 2 2: Isgalamido captured the BLUE flag!",
+	"  1:20 Kill: 3 2 10: Mocinha killed Isgalamido by MOD_RAILGUN",
+	"  2:30 Flag: 3 2: Mocinha captured the RED flag!",
+	"  3:00 ShutdownGame:",
+	"  3:01 Flag: 3 2: Mocinha captured the RED flag!",
+}
+
+// captures registers a Flag handler in registry that counts the captures
+// of each player by name.
+func captures(registry *parser.Registry) map[string]int {
+	count := map[string]int{}
+	registry.Register("Flag", parser.MatchHandler(func(timestamp time.Duration, payload string, match *parser.Match) error {
+		var id, action int
+		if _, err := fmt.Sscanf(payload, "%d %d:", &id, &action); err != nil {
+			return err
+		}
+		if index := match.PlayerIndex(id); index != -1 && action == 2 {
+			count[match.Players[index].Name]++
+		}
+		return nil
+	}))
+	return count
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("Custom events", func(t *testing.T) {
+		registry := parser.NewRegistry()
+		count := captures(registry)
+		matches := []parser.Match{}
+		stream := parser.Stream{Registry: registry, OnMatchEnd: func(index int, match parser.Match) error {
+			matches = append(matches, match)
+			return nil
+		}}
+		assert.Nil(t, parseWith(&stream, _flagLog))
+		assert.Equal(t, map[string]int{"Isgalamido": 1, "Mocinha": 1}, count)
+		assert.Len(t, matches, 1)
+		assert.Len(t, matches[0].Events, 1)
+	})
+
+	t.Run("Built-in handler replaced", func(t *testing.T) {
+		registry := parser.NewRegistry()
+		registry.Register("Kill", parser.MatchHandler(func(timestamp time.Duration, payload string, match *parser.Match) error {
+			match.Events = append(match.Events, parser.Kill{KillerID: 1022, VictimID: 2, MeanOfDeath: int(timestamp / time.Second)})
+			return nil
+		}))
+		matches := []parser.Match{}
+		for _, line := range _flagLog[:7] {
+			assert.Nil(t, registry.ParseLine(len(matches)-1, &matches, line))
+		}
+		assert.Equal(t, []parser.Kill{{KillerID: 1022, VictimID: 2, MeanOfDeath: 80}}, matches[0].Events)
+	})
+
+	t.Run("Event without a handler", func(t *testing.T) {
+		registry := parser.NewRegistry()
+		registry.Register("Kill", nil)
+		_, ok := registry.Handler("Kill")
+		assert.False(t, ok)
+		matches := []parser.Match{}
+		for _, line := range _flagLog[:7] {
+			assert.Nil(t, registry.ParseLine(len(matches)-1, &matches, line))
+		}
+		assert.Empty(t, matches[0].Events)
+	})
+
+	t.Run("Error of a handler", func(t *testing.T) {
+		registry := parser.NewRegistry()
+		registry.Register("Flag", parser.MatchHandler(func(timestamp time.Duration, payload string, match *parser.Match) error {
+			return errors.New("Invalid flag")
+		}))
+		stream := parser.Stream{Registry: registry}
+		assert.Equal(t, errors.New("Invalid flag"), parseWith(&stream, _flagLog))
+	})
+
+	t.Run("Parallel parser with a registry", func(t *testing.T) {
+		registry := parser.NewRegistry()
+		count := captures(registry)
+		parallel := parser.Parallel{Workers: 1, Registry: registry}
+		assert.Nil(t, parseWith(&parallel, append(append([]string{}, _flagLog...), _flagLog...)))
+		assert.Equal(t, map[string]int{"Isgalamido": 2, "Mocinha": 2}, count)
+	})
+
+	t.Run("Global handler in every dialect", func(t *testing.T) {
+		base := parser.NewRegistry()
+		dialects := parser.NewDialects(base)
+		count := 0
+		parser.RegisterIn(base, dialects, "Flag", parser.MatchHandler(func(timestamp time.Duration, payload string, match *parser.Match) error {
+			count++
+			return nil
+		}))
+		for _, dialect := range dialects {
+			_, ok := dialect.Registry().Handler("Flag")
+			assert.True(t, ok, dialect.Name())
+			assert.Nil(t, parseWith(&parser.Stream{Dialect: dialect}, _flagLog), dialect.Name())
+		}
+		_, ok := base.Handler("Flag")
+		assert.True(t, ok)
+		assert.Equal(t, 8, count)
+		_, ok = parser.DefaultRegistry.Handler("Flag")
+		assert.False(t, ok)
+	})
+
+	t.Run("Built-in events in DefaultRegistry", func(t *testing.T) {
+		for _, event := range []string{"InitGame", "ClientConnect", "ClientUserinfo", "ClientUserinfoChanged", "Kill"} {
+			_, ok := parser.DefaultRegistry.Handler(event)
+			assert.True(t, ok, event)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package parser
+
+import "time"
+
+// Stream parses a Quake 3 Arena Server log one line at a time keeping
+// only the match being played in memory. Each match is handed to
+// OnMatchEnd as soon as its ShutdownGame, the next InitGame or the end
+// of the log is seen. Lines between a ShutdownGame and the next InitGame
+// are ignored, since they don't belong to any match.
+type Stream struct {
+	// OnMatchEnd receives the 1-based index of the match in the log and
+	// the match itself. If it returns an error, parsing stops with it.
+	OnMatchEnd func(index int, match Match) error
+	// OnEvent, if set, receives every event line parsed into a match, after
+	// the match was updated by it, including InitGame and ShutdownGame.
+	OnEvent func(event Event) error
+	// Registry handles the events of the log. If not set, the Registry of
+	// the Dialect is used.
+	Registry *Registry
+	// Dialect is the syntax of the log. If not set, it's detected by the
+	// InitGame line of each match.
+	Dialect Dialect
+
+	matches  []Match
+	count    int
+	detected Dialect
+}
+
+// Event is an event line of the log along with the match it belongs to.
+type Event struct {
+	// Name is the event keyword, like InitGame or Kill.
+	Name    string
+	Time    time.Duration
+	Payload string
+	// Index is the 1-based index of the match in the log.
+	Index int
+	// Match is the state of the match right after the event.
+	Match Match
+}
+
+// ParseLine parses a line of the log with the handlers of the Registry.
+// Lines that are not events, like the dashed separators, are skipped.
+func (s *Stream) ParseLine(line string) error {
+	value, name, payload, ok := Tokenize(line)
+	if !ok {
+		return nil
+	}
+	switch name {
+	case "InitGame":
+		if err := s.Close(); err != nil {
+			return err
+		}
+		s.count++
+	case "ShutdownGame":
+		if len(s.matches) > 0 {
+			endTime, err := ParseTimestamp(value)
+			if err != nil {
+				return err
+			}
+			s.matches[0].EndTime = endTime
+		}
+		if err := s.emit(value, name, payload); err != nil {
+			return err
+		}
+		return s.Close()
+	default:
+		if len(s.matches) == 0 && s.count > 0 {
+			return nil
+		}
+	}
+	if err := s.registry().ParseLine(len(s.matches)-1, &s.matches, line); err != nil {
+		return err
+	}
+	return s.emit(value, name, payload)
+}
+
+// registry returns the Registry that handles the lines of the match being
+// played, detecting its dialect when needed.
+func (s *Stream) registry() *Registry {
+	switch {
+	case s.Registry != nil:
+		return s.Registry
+	case s.Dialect != nil:
+		return s.Dialect.Registry()
+	case len(s.matches) == 0:
+		return DefaultRegistry
+	}
+	if s.detected == nil {
+		s.detected = DetectDialect(s.matches[0].Settings)
+	}
+	return s.detected.Registry()
+}
+
+// emit hands an event line, already split by Tokenize, to OnEvent.
+func (s *Stream) emit(value, name, payload string) error {
+	if s.OnEvent == nil || len(s.matches) == 0 {
+		return nil
+	}
+	timestamp, err := ParseTimestamp(value)
+	if err != nil {
+		return err
+	}
+	return s.OnEvent(Event{
+		Name:    name,
+		Time:    timestamp,
+		Payload: payload,
+		Index:   s.count,
+		Match:   s.matches[0],
+	})
+}
+
+// Close ends the match being played, if any, handing it to OnMatchEnd.
+func (s *Stream) Close() error {
+	if len(s.matches) == 0 {
+		return nil
+	}
+	match := s.matches[0]
+	s.matches = s.matches[:0]
+	s.detected = nil
+	if s.OnMatchEnd == nil {
+		return nil
+	}
+	return s.OnMatchEnd(s.count, match)
+}
+
+// Current returns the index and the state of the match being played,
+// or false if no match is running.
+func (s *Stream) Current() (int, Match, bool) {
+	if len(s.matches) == 0 {
+		return 0, Match{}, false
+	}
+	return s.count, s.matches[0], true
+}
+
+// StreamState is what a Stream needs to continue parsing a log from
+// where another Stream stopped.
+type StreamState struct {
+	// Count is how many matches were started so far.
+	Count int `json:"count"`
+	// Match is the match being played, if any.
+	Match *Match `json:"match,omitempty"`
+}
+
+// State returns the current state of the Stream.
+func (s *Stream) State() StreamState {
+	state := StreamState{Count: s.count}
+	if len(s.matches) > 0 {
+		match := s.matches[0]
+		state.Match = &match
+	}
+	return state
+}
+
+// Restore makes the Stream continue from state, as returned by State.
+func (s *Stream) Restore(state StreamState) {
+	s.count = state.Count
+	s.matches = s.matches[:0]
+	s.detected = nil
+	if state.Match != nil {
+		s.matches = append(s.matches, *state.Match)
+	}
+}
@@ -0,0 +1,367 @@
+package parser_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name          string
+		lines         []string
+		want          map[int]parser.Match
+		expectError   bool
+		expectedError string
+	}{
+		{
+			name:  "No matches",
+			lines: []string{"  0:00 ------------------------------------------------------------"},
+			want:  map[int]parser.Match{},
+		},
+		{
+			name: "Match ended by ShutdownGame",
+			lines: []string{
+				`  0:00 InitGame: \mapname\q3dm17`,
+				" 20:34 ClientConnect: 2",
+				` 20:34 ClientUserinfoChanged: 2 n\Isgalamido\t\0`,
+				" 20:37 ShutdownGame:",
+				" 20:37 ------------------------------------------------------------",
+				" 20:38 ClientConnect: 3",
+			},
+			want: map[int]parser.Match{
+				1: {
+					Players: []parser.Player{
+						{
+							ID:        2,
+							Name:      "Isgalamido",
+							Connected: 20*time.Minute + 34*time.Second,
+						},
+					},
+					Events:   []parser.Kill{},
+					EndTime:  20*time.Minute + 37*time.Second,
+					Settings: map[string]string{"mapname": "q3dm17"},
+				},
+			},
+		},
+		{
+			name: "Match ended by the next InitGame and by Close",
+			lines: []string{
+				`  0:00 InitGame: \mapname\q3dm17`,
+				" 20:34 ClientConnect: 2",
+				` 20:37 InitGame: \mapname\q3dm6`,
+				" 20:38 ClientConnect: 3",
+				" 20:40 Kill: 1022 3 22: <world> killed Mocinha by MOD_TRIGGER_HURT",
+			},
+			want: map[int]parser.Match{
+				1: {
+					Players: []parser.Player{
+						{
+							ID:        2,
+							Name:      "",
+							Connected: 20*time.Minute + 34*time.Second,
+						},
+					},
+					Events:   []parser.Kill{},
+					Settings: map[string]string{"mapname": "q3dm17"},
+				},
+				2: {
+					Players: []parser.Player{
+						{
+							ID:        3,
+							Name:      "",
+							Connected: 20*time.Minute + 38*time.Second,
+						},
+					},
+					Events: []parser.Kill{
+						{
+							KillerID:    1022,
+							VictimID:    3,
+							MeanOfDeath: 22,
+							Time:        20*time.Minute + 40*time.Second,
+						},
+					},
+					StartTime: 20*time.Minute + 37*time.Second,
+					Settings:  map[string]string{"mapname": "q3dm6"},
+				},
+			},
+		},
+		{
+			name:          "Player connecting before any match",
+			lines:         []string{" 20:34 ClientConnect: 2"},
+			want:          map[int]parser.Match{},
+			expectError:   true,
+			expectedError: "ClientConnect line without an initialized match",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[int]parser.Match{}
+			stream := parser.Stream{
+				OnMatchEnd: func(index int, match parser.Match) error {
+					got[index] = parser.WithoutIndexes([]parser.Match{match})[0]
+					return nil
+				},
+			}
+			var err error
+			for _, line := range tt.lines {
+				if err = stream.ParseLine(line); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = stream.Close()
+			}
+			if tt.expectError {
+				if assert.Error(t, err) {
+					expected := errors.New(tt.expectedError)
+					assert.Equal(t, expected, err)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStreamCurrent(t *testing.T) {
+	stream := parser.Stream{}
+	_, _, ok := stream.Current()
+	assert.False(t, ok)
+
+	assert.NoError(t, stream.ParseLine(`  0:00 InitGame: \mapname\q3dm17`))
+	assert.NoError(t, stream.ParseLine(" 20:34 ClientConnect: 2"))
+	index, match, ok := stream.Current()
+	assert.True(t, ok)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, []parser.Player{{ID: 2, Name: "", Connected: 20*time.Minute + 34*time.Second}}, match.Players)
+
+	assert.NoError(t, stream.ParseLine(" 20:37 ShutdownGame:"))
+	_, _, ok = stream.Current()
+	assert.False(t, ok)
+}
+
+func TestStreamOnEvent(t *testing.T) {
+	got := []parser.Event{}
+	stream := parser.Stream{
+		OnEvent: func(event parser.Event) error {
+			event.Match = parser.Match{Players: event.Match.Players}
+			got = append(got, event)
+			return nil
+		},
+	}
+	lines := []string{
+		`  0:00 InitGame: \mapname\q3dm17`,
+		" 20:34 ClientConnect: 2",
+		" 20:37 ShutdownGame:",
+		" 20:37 ------------------------------------------------------------",
+	}
+	for _, line := range lines {
+		assert.NoError(t, stream.ParseLine(line))
+	}
+	assert.Equal(t, []parser.Event{
+		{
+			Name:    "InitGame",
+			Time:    0,
+			Payload: `\mapname\q3dm17`,
+			Index:   1,
+			Match:   parser.Match{Players: []parser.Player{}},
+		},
+		{
+			Name:    "ClientConnect",
+			Time:    20*time.Minute + 34*time.Second,
+			Payload: "2",
+			Index:   1,
+			Match:   parser.Match{Players: []parser.Player{{ID: 2, Name: "", Connected: 20*time.Minute + 34*time.Second}}},
+		},
+		{
+			Name:    "ShutdownGame",
+			Time:    20*time.Minute + 37*time.Second,
+			Payload: "",
+			Index:   1,
+			Match:   parser.Match{Players: []parser.Player{{ID: 2, Name: "", Connected: 20*time.Minute + 34*time.Second}}},
+		},
+	}, got)
+}
+
+func TestStreamRestore(t *testing.T) {
+	lines := []string{
+		`  0:00 InitGame: \mapname\q3dm17`,
+		" 20:34 ClientConnect: 2",
+		` 20:34 ClientUserinfoChanged: 2 n\Isgalamido\t\0`,
+		" 20:37 ShutdownGame:",
+		` 20:37 InitGame: \mapname\q3dm6`,
+		" 20:38 ClientConnect: 3",
+		` 20:38 ClientUserinfoChanged: 3 n\Mocinha\t\0`,
+		" 20:40 Kill: 1022 3 22: <world> killed Mocinha by MOD_TRIGGER_HURT",
+		" 20:41 ShutdownGame:",
+	}
+	parse := func(stream *parser.Stream, lines []string) map[int]parser.Match {
+		got := map[int]parser.Match{}
+		stream.OnMatchEnd = func(index int, match parser.Match) error {
+			got[index] = match
+			return nil
+		}
+		for _, line := range lines {
+			assert.NoError(t, stream.ParseLine(line))
+		}
+		return got
+	}
+
+	whole := parse(&parser.Stream{}, lines)
+	for split := range lines {
+		first := parser.Stream{}
+		got := parse(&first, lines[:split])
+		second := parser.Stream{}
+		second.Restore(first.State())
+		for index, match := range parse(&second, lines[split:]) {
+			got[index] = match
+		}
+		assert.Equal(t, whole, got, "split at line %d", split)
+	}
+}
+
+func TestStreamSlotReuse(t *testing.T) {
+	lines := []string{
+		`  0:00 InitGame: \mapname\q3dm17`,
+		" 0:01 ClientConnect: 2",
+		` 0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0`,
+		" 0:02 ClientConnect: 3",
+		` 0:02 ClientUserinfoChanged: 3 n\Mocinha\t\0`,
+		" 0:10 Kill: 2 3 10: Isgalamido killed Mocinha by MOD_RAILGUN",
+		" 0:20 ClientDisconnect: 2",
+		" 0:30 ClientConnect: 2",
+		` 0:30 ClientUserinfoChanged: 2 n\Zeh\t\0`,
+		" 0:40 Kill: 3 2 6: Mocinha killed Zeh by MOD_ROCKET",
+		" 0:50 ShutdownGame:",
+	}
+	var beforeReuse parser.Match
+	var ended parser.Match
+	stream := parser.Stream{
+		OnEvent: func(event parser.Event) error {
+			if event.Name == "ClientDisconnect" {
+				beforeReuse = event.Match
+			}
+			return nil
+		},
+		OnMatchEnd: func(index int, match parser.Match) error {
+			ended = match
+			return nil
+		},
+	}
+	for _, line := range lines {
+		assert.NoError(t, stream.ParseLine(line))
+	}
+
+	assert.Equal(t, []parser.Player{
+		{ID: 2, Name: "Isgalamido", Connected: time.Second, Disconnected: 20 * time.Second},
+		{ID: 3, Name: "Mocinha", Connected: 2 * time.Second},
+		{ID: 2, Name: "Zeh", Connected: 30 * time.Second},
+	}, ended.Players)
+	assert.Equal(t, 2, ended.PlayerIndex(2))
+	assert.Equal(t, 1, ended.PlayerIndex(3))
+	assert.Equal(t, -1, ended.PlayerIndex(4))
+	assert.Equal(t, 0, beforeReuse.PlayerIndex(2))
+	assert.Equal(t, 0, ended.PlayerAt(2, 20*time.Second))
+	assert.Equal(t, 2, ended.PlayerAt(2, 30*time.Second))
+	assert.Equal(t, 2, ended.PlayerAt(2, 0))
+	assert.Equal(t, -1, ended.PlayerAt(3, time.Second))
+	assert.Equal(t, 0, ended.Killer(ended.Events[0]))
+	assert.Equal(t, 1, ended.Victim(ended.Events[0]))
+	assert.Equal(t, 1, ended.Killer(ended.Events[1]))
+	assert.Equal(t, 2, ended.Victim(ended.Events[1]))
+	assert.Equal(t, -1, ended.Killer(parser.Kill{KillerID: 1022, VictimID: 3}))
+
+	decoded := parser.Match{Players: ended.Players}
+	assert.Equal(t, 2, decoded.PlayerIndex(2))
+}
+
+func TestStreamOnEventConcurrentReads(t *testing.T) {
+	lines := []string{`  0:00 InitGame: \mapname\q3dm17`}
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf(" 0:%02d ClientConnect: %d", i, i%8))
+	}
+	events := make(chan parser.Event, len(lines))
+	done := make(chan []int)
+	go func() {
+		slots := []int{}
+		for event := range events {
+			slots = append(slots, event.Match.PlayerIndex(0))
+		}
+		done <- slots
+	}()
+	stream := parser.Stream{OnEvent: func(event parser.Event) error {
+		events <- event
+		return nil
+	}}
+	for _, line := range lines {
+		assert.NoError(t, stream.ParseLine(line))
+	}
+	close(events)
+
+	// Each event keeps the index of the slots it was handed with, while the
+	// stream goes on connecting players to the same slots.
+	slots := <-done
+	assert.Equal(t, -1, slots[0])
+	for i := 1; i < len(slots); i++ {
+		assert.Equal(t, (i-1)/8*8, slots[i], "event %d", i)
+	}
+}
+
+func TestMatchCopy(t *testing.T) {
+	lines := []string{
+		`  0:00 InitGame: \mapname\q3dm17`,
+		" 0:01 ClientConnect: 2",
+		` 0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0`,
+		" 0:02 ClientConnect: 3",
+		` 0:02 ClientUserinfoChanged: 3 n\Mocinha\t\0`,
+		" 0:10 Kill: 2 3 10: Isgalamido killed Mocinha by MOD_RAILGUN",
+	}
+	stream := parser.Stream{}
+	for _, line := range lines {
+		assert.NoError(t, stream.ParseLine(line))
+	}
+	_, current, _ := stream.Current()
+	copied := current.Copy()
+	assert.Equal(t, parser.WithoutIndexes([]parser.Match{current}), []parser.Match{copied})
+
+	assert.NoError(t, stream.ParseLine(` 0:20 ClientUserinfoChanged: 2 n\Zeh\t\0`))
+	assert.NoError(t, stream.ParseLine(" 0:30 Kill: 3 2 10: Mocinha killed Zeh by MOD_RAILGUN"))
+	assert.Equal(t, "Isgalamido", copied.Players[0].Name)
+	assert.Equal(t, 1, len(copied.Events))
+	assert.Equal(t, "q3dm17", copied.MapName())
+	assert.Equal(t, 1, copied.PlayerIndex(3))
+}
+
+// generateCrowdedMatch writes a match of a server with 64 slots, all of them
+// taken, and many kills between them.
+func generateCrowdedMatch(kills int) []string {
+	lines := []string{`  0:00 InitGame: \mapname\q3dm17\sv_maxclients\64`}
+	for id := 0; id < 64; id++ {
+		lines = append(lines,
+			fmt.Sprintf("  0:01 ClientConnect: %d", id),
+			fmt.Sprintf(`  0:01 ClientUserinfoChanged: %d n\Player%d\t\0`, id, id),
+		)
+	}
+	for i := 0; i < kills; i++ {
+		lines = append(lines, fmt.Sprintf("  1:00 Kill: %d %d 10: killed by MOD_RAILGUN", 63-i%64, i*7%64))
+	}
+	return lines
+}
+
+func BenchmarkStreamCrowded(b *testing.B) {
+	lines := generateCrowdedMatch(5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stream := parser.Stream{OnMatchEnd: func(index int, match parser.Match) error { return nil }}
+		if err := parseWith(&stream, lines); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
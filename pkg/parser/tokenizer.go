@@ -0,0 +1,108 @@
+package parser
+
+import "strings"
+
+// Tokenize splits an event line of the log into its timestamp, event name
+// and payload, reading the first "minutes:seconds Name: payload" found in
+// it. The space after the colon is optional, since some events, like
+// ShutdownGame, have no payload at all. It returns false when the line is
+// not an event, like the dashed separators. Tokenize doesn't allocate: the
+// values returned are slices of line.
+func Tokenize(line string) (timestamp, event, payload string, ok bool) {
+	for i := 0; i < len(line); i++ {
+		// Starting in the middle of a number would read the same event
+		// as starting at its first digit, which was already tried.
+		if !isDigit(line[i]) || (i > 0 && isDigit(line[i-1])) {
+			continue
+		}
+		if timestamp, event, payload, ok = tokenizeAt(line, i); ok {
+			return timestamp, event, payload, true
+		}
+	}
+	return "", "", "", false
+}
+
+func tokenizeAt(line string, start int) (string, string, string, bool) {
+	colon := skipDigits(line, start)
+	if colon == start || colon == len(line) || line[colon] != ':' {
+		return "", "", "", false
+	}
+	space := skipDigits(line, colon+1)
+	if space == colon+1 || space == len(line) || line[space] != ' ' {
+		return "", "", "", false
+	}
+	end := space + 1
+	for end < len(line) && isWord(line[end]) {
+		end++
+	}
+	if end == space+1 || end == len(line) || line[end] != ':' {
+		return "", "", "", false
+	}
+	payload := end + 1
+	if payload < len(line) && isSpace(line[payload]) {
+		payload++
+	}
+	rest := line[payload:]
+	if newline := strings.IndexByte(rest, '\n'); newline != -1 {
+		rest = rest[:newline]
+	}
+	return line[start:space], line[space+1 : end], rest, true
+}
+
+// splitUserinfoChanged splits the payload of ClientUserinfoChanged, like
+// "2 n\Isgalamido\t\0", into the client id, the name, which are the word
+// characters right after n\, and the userinfo after the id.
+func splitUserinfoChanged(payload string) (id, name, info string, ok bool) {
+	space := skipDigits(payload, 0)
+	if space == 0 || !strings.HasPrefix(payload[space:], ` n\`) || strings.IndexByte(payload, '\n') != -1 {
+		return "", "", "", false
+	}
+	start := space + 3
+	end := start
+	for end < len(payload) && isWord(payload[end]) {
+		end++
+	}
+	if end == start {
+		return "", "", "", false
+	}
+	return payload[:space], payload[start:end], payload[space+1:], true
+}
+
+// splitKill splits the payload of Kill, like "1022 2 22: <world> killed
+// Isgalamido by MOD_TRIGGER_HURT", into the ids of the killer, the victim
+// and the mean of death.
+func splitKill(payload string) (killer, victim, mean string, ok bool) {
+	if strings.IndexByte(payload, '\n') != -1 {
+		return "", "", "", false
+	}
+	var ids [3]string
+	pos := 0
+	for i, separator := range []string{" ", " ", ": "} {
+		end := skipDigits(payload, pos)
+		if end == pos || !strings.HasPrefix(payload[end:], separator) {
+			return "", "", "", false
+		}
+		ids[i] = payload[pos:end]
+		pos = end + len(separator)
+	}
+	return ids[0], ids[1], ids[2], true
+}
+
+func skipDigits(s string, i int) int {
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isWord(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
@@ -0,0 +1,106 @@
+package parser_test
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+// _eventLine is the regular expression Tokenize replaced, kept to check
+// both read lines the same way and to compare their speed.
+var _eventLine = regexp.MustCompile(`(\d+:\d+) (\w+):\s?(.*)`)
+
+var _tokenizeLines = []string{
+	"",
+	"  0:00 ------------------------------------------------------------",
+	`  0:00 InitGame: \sv_floodProtect\1\sv_maxPing\0\mapname\q3dm17`,
+	" 20:37 ShutdownGame:",
+	" 20:37 ShutdownGame:\n",
+	" 20:34 ClientConnect: 2",
+	` 20:34 ClientUserinfoChanged: 2 n\Isgalamido\t\0\model\xian/default`,
+	" 22:06 Kill: 2 3 7: Isgalamido killed Mocinha by MOD_ROCKET_SPLASH",
+	" 22:06 Kill:\t2 3 7: tab instead of space",
+	" 22:06 Kill:\n2 3 7: newline after the colon\nand after the payload",
+	"981:27 Item: 2 weapon_rocketlauncher",
+	"1:2:3 say: Isgalamido: a timestamp with three parts",
+	"12:00 say: Mocinha: 1:00 InitGame: not really an InitGame",
+	"garbage 12:34 Exit: Fraglimit hit.",
+	"12:34Exit: no space before the event",
+	"12: 34 Exit: space inside the timestamp",
+	"12:34 : no event name",
+	"12:34 Exit no colon",
+	"12:34 Exit",
+	"12:34 ",
+	"00012:034 Exit: leading zeroes",
+	"12:34 Exit:Fraglimit hit.",
+	"12:34 Éxit: not a word character",
+	"12:34 Exit\xff: invalid utf-8",
+}
+
+func TestTokenize(t *testing.T) {
+	lines := append([]string{}, _tokenizeLines...)
+	alphabet := []string{"0", "1", "9", ":", " ", "\t", "\n", "a", "Z", "_", "-", `\`, "Kill", "é"}
+	random := rand.New(rand.NewSource(41))
+	for i := 0; i < 5000; i++ {
+		var b strings.Builder
+		for j := random.Intn(24); j > 0; j-- {
+			b.WriteString(alphabet[random.Intn(len(alphabet))])
+		}
+		lines = append(lines, b.String())
+	}
+
+	for _, line := range lines {
+		timestamp, event, payload, ok := parser.Tokenize(line)
+		want := _eventLine.FindStringSubmatch(line)
+		if assert.Equal(t, want != nil, ok, "%q", line) && ok {
+			assert.Equal(t, want[1:], []string{timestamp, event, payload}, "%q", line)
+		}
+	}
+}
+
+func TestTokenizeAllocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, line := range _tokenizeLines {
+			parser.Tokenize(line)
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	lines := generateLog(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			parser.Tokenize(line)
+		}
+	}
+}
+
+func BenchmarkTokenizeRegexp(b *testing.B) {
+	lines := generateLog(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			_eventLine.FindStringSubmatch(line)
+		}
+	}
+}
+
+func BenchmarkStream(b *testing.B) {
+	lines := generateLog(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stream := parser.Stream{OnMatchEnd: func(index int, match parser.Match) error { return nil }}
+		if err := parseWith(&stream, lines); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
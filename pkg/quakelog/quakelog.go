@@ -0,0 +1,259 @@
+// Package quakelog parses Quake 3 Arena Server logs and builds their
+// reports. It's the entry point for programs that embed quake-log:
+//
+//	matches, diagnostics, err := quakelog.Parse(file, quakelog.Options{Source: "games.log"})
+//	report := quakelog.NewReport(quakelog.WithMeansOfDeath(), quakelog.WithoutBots())
+//	for _, match := range matches {
+//		report.Add(match)
+//	}
+//	entries := report.Entries()
+package quakelog
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/reesilva/quake-log/pkg/input"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+)
+
+// Options changes how a log is parsed. The zero value parses a log one
+// match at a time, detecting its dialect, and stops at the first error.
+type Options struct {
+	// Source names the log in its matches, like the path of its file.
+	Source string
+	// Dialect is the syntax of the log. If not set, it's detected by the
+	// InitGame line of each match.
+	Dialect parser.Dialect
+	// Registry, if set, handles the events of the log instead of the
+	// Registry of the dialect.
+	Registry *parser.Registry
+	// Workers is how many matches are parsed at the same time. Matches are
+	// still returned in the order they were played. Up to 1, they are
+	// parsed one at a time by the goroutine reading the log.
+	Workers int
+	// OnMatch, if set, receives each match as soon as it ends and they are
+	// not returned, so only the match being played is kept in memory. If
+	// it returns an error, parsing stops with it.
+	OnMatch func(match Match) error
+	// SkipErrors skips the lines that can't be parsed, keeping them in the
+	// Diagnostics, instead of stopping at the first one. The lines are
+	// parsed one at a time, whatever the number of Workers.
+	SkipErrors bool
+	// OnProgress, if set, is called every few thousand lines with how much
+	// of the log was parsed so far, and once more when it ends.
+	OnProgress func(progress Progress)
+}
+
+// _progressLines is how many lines are parsed between calls to OnProgress.
+const _progressLines = 4096
+
+// Progress is how much of a log was parsed so far.
+type Progress struct {
+	// Bytes is how many bytes of the log were read.
+	Bytes   int64
+	Lines   int
+	Matches int
+	Errors  int
+}
+
+// Match is a match parsed from a log, along with where it was played.
+type Match struct {
+	// Source is the Source of the Options the match was parsed with.
+	Source string
+	// Index is the 1-based position of the match in its log.
+	Index int
+	// ID is the output.MatchID of the match, as it was parsed, or the
+	// one given by output.MatchIDs when it's identical to a match before
+	// it in the log.
+	ID string
+	// Errors are the lines skipped by SkipErrors since the previous match
+	// ended, which are the lines of this match that couldn't be parsed.
+	Errors []LineError
+	parser.Match
+}
+
+// NewMatch identifies a match parsed at index of the source log, with ids
+// given to the matches of the log parsed before it.
+func NewMatch(source string, index int, match parser.Match, ids *output.MatchIDs) Match {
+	return Match{
+		Source: source,
+		Index:  index,
+		ID:     ids.ID(index, match),
+		Match:  match,
+	}
+}
+
+// Diagnostics describes how a log was parsed.
+type Diagnostics struct {
+	// Bytes is how many bytes of the log were read.
+	Bytes int64
+	// Lines is how many lines were read.
+	Lines int
+	// Matches is how many matches ended, including the ones handed to
+	// OnMatch.
+	Matches int
+	// Errors are the lines skipped by SkipErrors.
+	Errors []LineError
+}
+
+// LineError is a line of the log that couldn't be parsed.
+type LineError struct {
+	// Line is the 1-based number of the line in the log.
+	Line int
+	Text string
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("Line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the error of the line.
+func (e LineError) Unwrap() error {
+	return e.Err
+}
+
+// Parse reads the whole log in r. On error, it returns the matches that
+// ended before it.
+func Parse(r io.Reader, opts Options) ([]Match, *Diagnostics, error) {
+	return ParseContext(context.Background(), r, opts)
+}
+
+// ParseContext works like Parse, but stops with the error of ctx when it's
+// done, returning the matches that ended before it.
+func ParseContext(ctx context.Context, r io.Reader, opts Options) ([]Match, *Diagnostics, error) {
+	p := NewParser(opts)
+	p.ReadContext(ctx, r)
+	return p.Close()
+}
+
+// Parser parses a log read in parts, like the rotated files of a log,
+// which are read from the oldest to the newest as a single log.
+type Parser struct {
+	opts  Options
+	lines interface {
+		ParseLine(line string) error
+		Close() error
+	}
+	matches     []Match
+	ids         output.MatchIDs
+	diagnostics Diagnostics
+	// ended is how many of the Errors were before the last match ended.
+	ended int
+	err   error
+}
+
+// NewParser returns a Parser configured by opts.
+func NewParser(opts Options) *Parser {
+	p := &Parser{
+		opts:        opts,
+		matches:     []Match{},
+		diagnostics: Diagnostics{Errors: []LineError{}},
+	}
+	if opts.Workers > 1 && !opts.SkipErrors {
+		p.lines = &parser.Parallel{
+			Workers:    opts.Workers,
+			Registry:   opts.Registry,
+			Dialect:    opts.Dialect,
+			OnMatchEnd: p.onMatchEnd,
+		}
+	} else {
+		p.lines = &parser.Stream{
+			Registry:   opts.Registry,
+			Dialect:    opts.Dialect,
+			OnMatchEnd: p.onMatchEnd,
+		}
+	}
+	return p
+}
+
+func (p *Parser) onMatchEnd(index int, match parser.Match) error {
+	p.diagnostics.Matches++
+	m := NewMatch(p.opts.Source, index, match, &p.ids)
+	if skipped := p.diagnostics.Errors[p.ended:]; len(skipped) > 0 {
+		m.Errors = skipped[:len(skipped):len(skipped)]
+		p.ended = len(p.diagnostics.Errors)
+	}
+	if p.opts.OnMatch == nil {
+		p.matches = append(p.matches, m)
+		return nil
+	}
+	// Errors of OnMatch always stop parsing, even with SkipErrors.
+	p.err = p.opts.OnMatch(m)
+	return p.err
+}
+
+// Read parses the lines of r, continuing the log read so far.
+func (p *Parser) Read(r io.Reader) error {
+	return p.ReadContext(context.Background(), r)
+}
+
+// ReadContext works like Read, but stops with the error of ctx when it's
+// done. The line being read when that happens is parsed first, so a read
+// blocked waiting for a line, like from a pipe, is not interrupted.
+func (p *Parser) ReadContext(ctx context.Context, r io.Reader) error {
+	if p.err != nil {
+		return p.err
+	}
+	scanner := bufio.NewScanner(input.CountingReader{Reader: r, Count: &p.diagnostics.Bytes})
+	for scanner.Scan() {
+		if p.err = ctx.Err(); p.err != nil {
+			return p.err
+		}
+		p.diagnostics.Lines++
+		if p.opts.OnProgress != nil && p.diagnostics.Lines%_progressLines == 0 {
+			p.opts.OnProgress(p.progress())
+		}
+		err := p.lines.ParseLine(scanner.Text())
+		if err == nil {
+			continue
+		}
+		if p.err != nil || !p.opts.SkipErrors {
+			p.err = err
+			return err
+		}
+		p.diagnostics.Errors = append(p.diagnostics.Errors, LineError{
+			Line: p.diagnostics.Lines,
+			Text: scanner.Text(),
+			Err:  err,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		p.err = err
+	}
+	return p.err
+}
+
+// Close ends the match being played, if any, and returns the matches of
+// the log, unless they were handed to OnMatch, and its Diagnostics.
+func (p *Parser) Close() ([]Match, *Diagnostics, error) {
+	switch lines := p.lines.(type) {
+	case *parser.Parallel:
+		// It stops its workers, without handing more matches to
+		// onMatchEnd after an error.
+		if err := lines.Close(); p.err == nil {
+			p.err = err
+		}
+	default:
+		if p.err == nil {
+			p.err = lines.Close()
+		}
+	}
+	if p.opts.OnProgress != nil {
+		p.opts.OnProgress(p.progress())
+	}
+	return p.matches, &p.diagnostics, p.err
+}
+
+func (p *Parser) progress() Progress {
+	return Progress{
+		Bytes:   p.diagnostics.Bytes,
+		Lines:   p.diagnostics.Lines,
+		Matches: p.diagnostics.Matches,
+		Errors:  len(p.diagnostics.Errors),
+	}
+}
@@ -0,0 +1,209 @@
+package quakelog_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/quakelog"
+	"github.com/stretchr/testify/assert"
+)
+
+const _log = `  0:00 ------------------------------------------------------------
+  0:00 InitGame: \sv_hostname\Code Miner Server\mapname\q3dm17
+  0:01 ClientConnect: 2
+  0:01 ClientUserinfoChanged: 2 n\Isgalamido\t\0
+  0:02 ClientConnect: 3
+  0:02 ClientUserinfoChanged: 3 n\Mocinha\t\0
+  0:03 ClientConnect: 4
+  0:03 ClientUserinfoChanged: 4 n\Sarge\t\0
+  0:03 ClientUserinfo: 4 \name\Sarge\skill\3
+  0:10 Kill: 2 3 10: Isgalamido killed Mocinha by MOD_RAILGUN
+  0:11 Kill: 1022 2 22: <world> killed Isgalamido by MOD_TRIGGER_HURT
+  0:12 Kill: 4 2 6: Sarge killed Isgalamido by MOD_ROCKET
+  0:20 ShutdownGame:
+  0:20 ------------------------------------------------------------
+  1:00 InitGame: \sv_hostname\Code Miner Server\mapname\q3dm6
+  1:01 ClientConnect: 2
+  1:01 ClientUserinfoChanged: 2 n\Isga\t\0
+  1:10 Kill: 1022 2 22: <world> killed Isga by MOD_TRIGGER_HURT
+  1:20 ShutdownGame:
+`
+
+const _brokenLine = "  0:09 Kill: 7 3 10: Nobody killed Mocinha by MOD_RAILGUN"
+
+func withLine(index int, line string) string {
+	lines := strings.SplitAfter(_log, "\n")
+	lines = append(lines[:index], append([]string{line + "\n"}, lines[index:]...)...)
+	return strings.Join(lines, "")
+}
+
+func TestParse(t *testing.T) {
+	stream := []parser.Match{}
+	p := parser.Stream{OnMatchEnd: func(index int, match parser.Match) error {
+		stream = append(stream, match)
+		return nil
+	}}
+	for _, line := range strings.Split(_log, "\n") {
+		assert.Nil(t, p.ParseLine(line))
+	}
+	assert.Nil(t, p.Close())
+
+	for _, workers := range []int{0, 1, 3} {
+		matches, diagnostics, err := quakelog.Parse(strings.NewReader(_log), quakelog.Options{Source: "games.log", Workers: workers})
+		assert.Nil(t, err)
+		assert.Equal(t, &quakelog.Diagnostics{Bytes: int64(len(_log)), Lines: 19, Matches: 2, Errors: []quakelog.LineError{}}, diagnostics)
+		assert.Equal(t, 2, len(matches))
+		for i, match := range matches {
+			assert.Equal(t, "games.log", match.Source)
+			assert.Equal(t, i+1, match.Index)
+			assert.Equal(t, output.MatchID(stream[i]), match.ID)
+			assert.Equal(t, stream[i].Events, match.Events)
+			assert.Equal(t, stream[i].Players, match.Players)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		log         string
+		opts        quakelog.Options
+		err         error
+		matches     int
+		diagnostics quakelog.Diagnostics
+	}{
+		{
+			name:        "First error with the matches before it",
+			log:         withLine(16, _brokenLine),
+			err:         errors.New("Kill by a non existent player"),
+			matches:     1,
+			diagnostics: quakelog.Diagnostics{Lines: 17, Matches: 1, Errors: []quakelog.LineError{}},
+		},
+		{
+			name:        "First error with many workers",
+			log:         withLine(16, _brokenLine),
+			opts:        quakelog.Options{Workers: 2},
+			err:         errors.New("Kill by a non existent player"),
+			matches:     1,
+			diagnostics: quakelog.Diagnostics{Lines: 20, Matches: 1, Errors: []quakelog.LineError{}},
+		},
+		{
+			name:    "Lines that can't be parsed",
+			log:     withLine(9, _brokenLine),
+			opts:    quakelog.Options{SkipErrors: true, Workers: 2},
+			matches: 2,
+			diagnostics: quakelog.Diagnostics{Lines: 20, Matches: 2, Errors: []quakelog.LineError{
+				{Line: 10, Text: _brokenLine, Err: errors.New("Kill by a non existent player")},
+			}},
+		},
+		{
+			name: "Error of OnMatch skipping errors",
+			log:  _log,
+			opts: quakelog.Options{SkipErrors: true, OnMatch: func(match quakelog.Match) error {
+				return errors.New("Stop")
+			}},
+			err:         errors.New("Stop"),
+			diagnostics: quakelog.Diagnostics{Lines: 13, Matches: 1, Errors: []quakelog.LineError{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, diagnostics, err := quakelog.Parse(strings.NewReader(tt.log), tt.opts)
+			// The log fits in the buffer of the first read.
+			tt.diagnostics.Bytes = int64(len(tt.log))
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.matches, len(matches))
+			assert.Equal(t, &tt.diagnostics, diagnostics)
+		})
+	}
+}
+
+func TestParseMatchErrors(t *testing.T) {
+	lines := strings.SplitAfter(_log, "\n")
+	log := strings.Join(lines[:9], "") + _brokenLine + "\n" + strings.Join(lines[9:17], "") + _brokenLine + "\n" + strings.Join(lines[17:], "")
+	matches, diagnostics, err := quakelog.Parse(strings.NewReader(log), quakelog.Options{SkipErrors: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(diagnostics.Errors))
+	assert.Equal(t, diagnostics.Errors[:1], matches[0].Errors)
+	assert.Equal(t, diagnostics.Errors[1:], matches[1].Errors)
+	assert.True(t, errors.Is(matches[0].Errors[0], parser.ErrUnknownKiller))
+}
+
+func TestLineError(t *testing.T) {
+	err := quakelog.LineError{Line: 10, Text: _brokenLine, Err: errors.New("Kill by a non existent player")}
+	assert.Equal(t, "Line 10: Kill by a non existent player", err.Error())
+	assert.Equal(t, errors.New("Kill by a non existent player"), errors.Unwrap(err))
+}
+
+func TestParserParts(t *testing.T) {
+	got := []quakelog.Match{}
+	p := quakelog.NewParser(quakelog.Options{Source: "games.log", OnMatch: func(match quakelog.Match) error {
+		got = append(got, match)
+		return nil
+	}})
+	// A match split between two rotated files is parsed as one.
+	half := strings.Index(_log, "  0:11")
+	assert.Nil(t, p.Read(strings.NewReader(_log[:half])))
+	assert.Empty(t, got)
+	assert.Nil(t, p.Read(strings.NewReader(_log[half:])))
+	matches, diagnostics, err := p.Close()
+	assert.Nil(t, err)
+	assert.Empty(t, matches)
+	assert.Equal(t, 2, diagnostics.Matches)
+
+	want, _, err := quakelog.Parse(strings.NewReader(_log), quakelog.Options{Source: "games.log"})
+	assert.Nil(t, err)
+	assert.Equal(t, len(want), len(got))
+	for i := range want {
+		assert.Equal(t, want[i].ID, got[i].ID)
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	matches, diagnostics, err := quakelog.ParseContext(ctx, strings.NewReader(_log), quakelog.Options{
+		OnMatch: func(match quakelog.Match) error {
+			cancel()
+			return nil
+		},
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, matches)
+	assert.Equal(t, 1, diagnostics.Matches)
+	assert.Equal(t, 13, diagnostics.Lines)
+
+	for _, workers := range []int{1, 2} {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		matches, _, err := quakelog.ParseContext(ctx, strings.NewReader(_log), quakelog.Options{Workers: workers})
+		assert.Equal(t, context.Canceled, err)
+		assert.Empty(t, matches)
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	log := strings.Repeat(_log, 500)
+	got := []quakelog.Progress{}
+	_, diagnostics, err := quakelog.Parse(strings.NewReader(log), quakelog.Options{
+		OnMatch: func(match quakelog.Match) error {
+			return nil
+		},
+		OnProgress: func(progress quakelog.Progress) {
+			got = append(got, progress)
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(got))
+	for i, progress := range got[:2] {
+		assert.Equal(t, (i+1)*4096, progress.Lines)
+		assert.True(t, progress.Bytes > 0)
+		assert.True(t, progress.Matches > 0)
+	}
+	assert.Equal(t, quakelog.Progress{Bytes: int64(len(log)), Lines: 9500, Matches: 1000}, got[2])
+	assert.Equal(t, got[2].Lines, diagnostics.Lines)
+}
@@ -0,0 +1,185 @@
+package quakelog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/awards"
+	"github.com/reesilva/quake-log/pkg/identity"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/query"
+)
+
+// Report builds the report of the matches added to it, changed by its
+// options. Bots are left out first, then players are renamed by their
+// identities and only then the filter is applied, so queries see the
+// canonical names.
+type Report struct {
+	meansOfDeath bool
+	withoutBots  bool
+	identities   *identity.Identities
+	filter       *query.Query
+	timeline     bool
+	interval     time.Duration
+	awards       *awards.Rules
+	scoring      *output.Scoring
+	server       string
+
+	matches []parser.Match
+	entries []output.MatchEntry
+}
+
+// ReportOption changes how a Report is built.
+type ReportOption func(r *Report)
+
+// WithMeansOfDeath adds the kills by each mean of death to the report of
+// every match.
+func WithMeansOfDeath() ReportOption {
+	return func(r *Report) {
+		r.meansOfDeath = true
+	}
+}
+
+// WithoutBots leaves out the bots and the kills they made or suffered.
+func WithoutBots() ReportOption {
+	return func(r *Report) {
+		r.withoutBots = true
+	}
+}
+
+// WithIdentities reports players by their canonical names. A nil ids
+// keeps the names as they were logged.
+func WithIdentities(ids *identity.Identities) ReportOption {
+	return func(r *Report) {
+		r.identities = ids
+	}
+}
+
+// WithFilter reports only the matches that satisfy q, with only the kills
+// that satisfy it when it has conditions on them. A nil q reports every
+// match.
+func WithFilter(q *query.Query) ReportOption {
+	return func(r *Report) {
+		r.filter = q
+	}
+}
+
+// WithTimeline adds the timeline of the scores to the report of every
+// match, sampled every interval or, if it's zero, after every kill. It's
+// left out of the Games.
+func WithTimeline(interval time.Duration) ReportOption {
+	return func(r *Report) {
+		r.timeline = true
+		r.interval = interval
+	}
+}
+
+// WithAwards adds the awards won by the rules to the report of every match.
+// A nil rules gives no awards. They're left out of the Games.
+func WithAwards(rules *awards.Rules) ReportOption {
+	return func(r *Report) {
+		r.awards = rules
+	}
+}
+
+// WithScoring adds the scores of the players by the rules of scoring to
+// the report of every match. They're left out of the Games.
+func WithScoring(scoring output.Scoring) ReportOption {
+	return func(r *Report) {
+		r.scoring = &scoring
+	}
+}
+
+// WithServer adds the name of the server the matches were played on to the
+// report of every match. It's left out of the Games.
+func WithServer(name string) ReportOption {
+	return func(r *Report) {
+		r.server = name
+	}
+}
+
+// NewReport returns an empty Report with opts applied.
+func NewReport(opts ...ReportOption) *Report {
+	r := &Report{
+		matches: []parser.Match{},
+		entries: []output.MatchEntry{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Entry returns the report of match, without adding it to r, or false if
+// the filter leaves it out. The entry keeps the ID of the match as parsed.
+func (r *Report) Entry(match Match) (output.MatchEntry, bool) {
+	entry, _, ok := r.build(match)
+	return entry, ok
+}
+
+// Add adds the report of match to r, returning false if the filter left it
+// out.
+func (r *Report) Add(match Match) bool {
+	entry, m, ok := r.build(match)
+	if ok {
+		r.matches = append(r.matches, m)
+		r.entries = append(r.entries, entry)
+	}
+	return ok
+}
+
+func (r *Report) build(match Match) (output.MatchEntry, parser.Match, bool) {
+	m := match.Match
+	if r.withoutBots {
+		m = m.WithoutBots()
+	}
+	if r.identities != nil {
+		m = r.identities.Apply(m)
+	}
+	if r.filter != nil {
+		var ok bool
+		if m, ok = r.filter.Match(match.Source, match.Index, m); !ok {
+			return output.MatchEntry{}, parser.Match{}, false
+		}
+	}
+	entry := output.CreateMatchEntry(match.Index, m, r.meansOfDeath)
+	entry.ID = match.ID
+	entry.Source = match.Source
+	entry.Server = r.server
+	if r.scoring != nil {
+		entry.Scores = output.CreateScores(m, *r.scoring)
+	}
+	if r.timeline {
+		timeline := output.CreateTimeline(m, r.interval, r.scoring)
+		entry.Timeline = &timeline
+	}
+	if r.awards != nil {
+		entry.Awards = r.awards.Match(m)
+	}
+	return entry, m, true
+}
+
+// Entries returns the reports of the matches added, in the order they were
+// added.
+func (r *Report) Entries() []output.MatchEntry {
+	return r.entries
+}
+
+// Games returns the reports of the matches added in the legacy layout,
+// keyed by game_N in the order they were added.
+func (r *Report) Games() (map[string]output.MatchReport, error) {
+	return output.CreateMatchReport(r.matches, r.meansOfDeath)
+}
+
+// Layout returns the report in the layout called name: "list", of the
+// Entries, or "map", of the Games.
+func (r *Report) Layout(name string) (interface{}, error) {
+	switch name {
+	case "list":
+		return r.Entries(), nil
+	case "map":
+		return r.Games()
+	}
+	return nil, fmt.Errorf("Unknown layout %q", name)
+}
@@ -0,0 +1,190 @@
+package quakelog_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/awards"
+	"github.com/reesilva/quake-log/pkg/identity"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/quakelog"
+	"github.com/reesilva/quake-log/pkg/query"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	matches, _, err := quakelog.Parse(strings.NewReader(_log), quakelog.Options{Source: "games.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := identity.New([]identity.Identity{{Name: "Isgalamido", Aliases: []string{"Isga"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := query.Parse("player = Isgalamido and weapon = trigger_hurt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    []quakelog.ReportOption
+		entries []output.MatchEntry
+	}{
+		{
+			name: "Every match",
+			entries: []output.MatchEntry{
+				{Source: "games.log", ID: matches[0].ID, Index: 1, Map: "q3dm17", StartTime: "0:00", Bots: []string{"Sarge"}, MatchReport: output.MatchReport{
+					TotalKills: 3,
+					Players:    []string{"Isgalamido", "Mocinha", "Sarge"},
+					Kills:      map[string]int{"Isgalamido": 1, "Sarge": 1},
+				}},
+				{Source: "games.log", ID: matches[1].ID, Index: 2, Map: "q3dm6", StartTime: "1:00", MatchReport: output.MatchReport{
+					TotalKills: 1,
+					Players:    []string{"Isga"},
+					Kills:      map[string]int{},
+				}},
+			},
+		},
+		{
+			name: "Kills by means without bots",
+			opts: []quakelog.ReportOption{quakelog.WithMeansOfDeath(), quakelog.WithoutBots()},
+			entries: []output.MatchEntry{
+				{Source: "games.log", ID: matches[0].ID, Index: 1, Map: "q3dm17", StartTime: "0:00", MatchReport: output.MatchReport{
+					TotalKills:   2,
+					Players:      []string{"Isgalamido", "Mocinha"},
+					Kills:        map[string]int{"Isgalamido": 1},
+					KillsByMeans: map[string]int{"MOD_RAILGUN": 1, "MOD_TRIGGER_HURT": 1},
+				}},
+				{Source: "games.log", ID: matches[1].ID, Index: 2, Map: "q3dm6", StartTime: "1:00", MatchReport: output.MatchReport{
+					TotalKills:   1,
+					Players:      []string{"Isga"},
+					Kills:        map[string]int{},
+					KillsByMeans: map[string]int{"MOD_TRIGGER_HURT": 1},
+				}},
+			},
+		},
+		{
+			name: "Canonical names",
+			opts: []quakelog.ReportOption{quakelog.WithIdentities(ids), quakelog.WithFilter(filter)},
+			entries: []output.MatchEntry{
+				{Source: "games.log", ID: matches[0].ID, Index: 1, Map: "q3dm17", StartTime: "0:00", Bots: []string{"Sarge"}, MatchReport: output.MatchReport{
+					TotalKills: 1,
+					Players:    []string{"Isgalamido", "Mocinha", "Sarge"},
+					Kills:      map[string]int{},
+				}},
+				{Source: "games.log", ID: matches[1].ID, Index: 2, Map: "q3dm6", StartTime: "1:00", MatchReport: output.MatchReport{
+					TotalKills: 1,
+					Players:    []string{"Isgalamido"},
+					Kills:      map[string]int{},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := quakelog.NewReport(tt.opts...)
+			for _, match := range matches {
+				entry, ok := report.Entry(match)
+				assert.True(t, ok)
+				assert.Equal(t, ok, report.Add(match))
+				assert.Equal(t, entry, report.Entries()[len(report.Entries())-1])
+			}
+			assert.Equal(t, tt.entries, report.Entries())
+		})
+	}
+}
+
+func TestReportLayout(t *testing.T) {
+	matches, _, err := quakelog.Parse(strings.NewReader(_log), quakelog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := query.Parse("map = q3dm6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := quakelog.NewReport(quakelog.WithFilter(filter))
+	assert.False(t, report.Add(matches[0]))
+	assert.True(t, report.Add(matches[1]))
+
+	list, err := report.Layout("list")
+	assert.Nil(t, err)
+	assert.Equal(t, report.Entries(), list)
+
+	games, err := report.Layout("map")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]output.MatchReport{
+		"game_1": {TotalKills: 1, Players: []string{"Isga"}, Kills: map[string]int{}},
+	}, games)
+
+	_, err = report.Layout("table")
+	assert.Equal(t, errors.New(`Unknown layout "table"`), err)
+}
+
+func TestReportTimeline(t *testing.T) {
+	matches, _, err := quakelog.Parse(strings.NewReader(_log), quakelog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := quakelog.NewReport(quakelog.WithTimeline(time.Minute), quakelog.WithoutBots())
+	for _, match := range matches {
+		report.Add(match)
+	}
+
+	assert.Equal(t, &output.Timeline{
+		Samples: []output.ScoreSample{
+			{Time: "0:20", Elapsed: "0:20", Scores: map[string]int{"Isgalamido": 1, "Mocinha": 0}},
+		},
+		LeadChanges:    []output.LeadChange{{Time: "0:10", Elapsed: "0:10", Leader: "Isgalamido", Score: 1}},
+		Winner:         "Isgalamido",
+		WinnerLeadTime: "0:10",
+	}, report.Entries()[0].Timeline)
+	assert.Equal(t, []output.ScoreSample{
+		{Time: "1:20", Elapsed: "0:20", Scores: map[string]int{"Isga": 0}},
+	}, report.Entries()[1].Timeline.Samples)
+}
+
+func TestReportAwards(t *testing.T) {
+	matches, _, err := quakelog.Parse(strings.NewReader(_log), quakelog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := awards.New([]awards.Rule{{Name: "Most suicides", Stat: "suicides", Conditions: []string{"suicides > 0"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := identity.New([]identity.Identity{{Name: "Isgalamido", Aliases: []string{"Isga"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := quakelog.NewReport(quakelog.WithAwards(rules), quakelog.WithIdentities(ids))
+	for _, match := range matches {
+		report.Add(match)
+	}
+
+	assert.Equal(t, []output.Award{{Name: "Most suicides", Players: []string{"Isgalamido"}, Value: 1}}, report.Entries()[0].Awards)
+	assert.Equal(t, []output.Award{{Name: "Most suicides", Players: []string{"Isgalamido"}, Value: 1}}, report.Entries()[1].Awards)
+}
+
+func TestReportScoresAndServer(t *testing.T) {
+	matches, _, err := quakelog.Parse(strings.NewReader(_log), quakelog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := quakelog.NewReport(quakelog.WithScoring(output.Scoring{Kill: 1, World: -1}), quakelog.WithServer("Code Miner"), quakelog.WithTimeline(0))
+	for _, match := range matches {
+		report.Add(match)
+	}
+
+	assert.Equal(t, "Code Miner", report.Entries()[0].Server)
+	assert.Equal(t, map[string]int{"Isgalamido": 0, "Mocinha": 0, "Sarge": 1}, report.Entries()[0].Scores)
+	assert.Equal(t, map[string]int{"Isga": -1}, report.Entries()[1].Scores)
+	for _, entry := range report.Entries() {
+		samples := entry.Timeline.Samples
+		assert.Equal(t, entry.Scores, samples[len(samples)-1].Scores)
+	}
+}
@@ -0,0 +1,424 @@
+// Package query filters Quake 3 Arena matches, and their kills, by the
+// --where queries of the commands, like "map = q3dm17 and kills > 50".
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+)
+
+// World is the name matched by the killer field for kills by the world,
+// like falling or drowning.
+const World = "<world>"
+
+// _worldID is the client slot of the world in the kills it makes.
+const _worldID = 1022
+
+// Query is a compiled filter over parsed matches, like
+//
+//	map = q3dm17 and kills > 50
+//	killer = Isgalamido and weapon = railgun
+//	player = Mocinha and (start >= 10:00 and start < 30:00)
+//
+// Comparisons are joined with and, or, not and parentheses. The fields of a
+// match are map, source, index, kills (the total of kills), players (how many
+// played), player (any of them by name) and start (the uptime of the server
+// when the match started). The fields of a kill are killer, victim and weapon,
+// the mean of death with or without the MOD_ prefix, like railgun or
+// UT_MOD_LR300.
+type Query struct {
+	root  node
+	kills bool
+}
+
+// Parse compiles expr into a Query or returns an error describing why
+// it's not valid.
+func Parse(expr string) (*Query, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &compiler{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected %q in query", p.tokens[p.pos].text)
+	}
+	return &Query{root: root, kills: p.kills}, nil
+}
+
+// Match reports whether the match of the log file source at index satisfies
+// the query. When the query has kill fields and only some kills can satisfy
+// it, like "killer = Isgalamido" but not "map = q3dm17 or killer =
+// Isgalamido" on q3dm17, the match is returned with only the kills that
+// satisfy it and it's kept only when there is at least one, so the report
+// counts just those kills.
+func (q *Query) Match(source string, index int, match parser.Match) (parser.Match, bool) {
+	env := env{source: source, index: index, match: match}
+	if result := q.root.eval(env); !q.kills || result != unknown {
+		return match, result == yes
+	}
+	events := []parser.Kill{}
+	for i := range match.Events {
+		env.kill = &match.Events[i]
+		if q.root.eval(env) == yes {
+			events = append(events, match.Events[i])
+		}
+	}
+	if len(events) == 0 {
+		return match, false
+	}
+	match.Events = events
+	return match, true
+}
+
+type env struct {
+	source string
+	index  int
+	match  parser.Match
+	// kill is the kill being evaluated, or nil when the match is, so the
+	// fields of kills are unknown.
+	kill *parser.Kill
+}
+
+// truth is the result of a node, which is unknown when it depends on the
+// fields of a kill and no kill is being evaluated.
+type truth int
+
+const (
+	no truth = iota
+	yes
+	unknown
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return yes
+	}
+	return no
+}
+
+type node interface {
+	eval(env env) truth
+}
+
+type and struct{ left, right node }
+
+func (n and) eval(env env) truth {
+	left, right := n.left.eval(env), n.right.eval(env)
+	switch {
+	case left == no || right == no:
+		return no
+	case left == yes && right == yes:
+		return yes
+	}
+	return unknown
+}
+
+type or struct{ left, right node }
+
+func (n or) eval(env env) truth {
+	left, right := n.left.eval(env), n.right.eval(env)
+	switch {
+	case left == yes || right == yes:
+		return yes
+	case left == no && right == no:
+		return no
+	}
+	return unknown
+}
+
+type not struct{ node node }
+
+func (n not) eval(env env) truth {
+	switch n.node.eval(env) {
+	case yes:
+		return no
+	case no:
+		return yes
+	}
+	return unknown
+}
+
+// compare tests the values of a field against a value. Fields with many
+// values, like player, are satisfied when any of them is.
+type compare struct {
+	values func(env env) []string
+	kill   bool
+	number bool
+	op     string
+	value  string
+	parsed int64
+}
+
+func (n compare) eval(env env) truth {
+	if n.kill && env.kill == nil {
+		return unknown
+	}
+	if n.op == "!=" {
+		return truthOf(!n.any(env, "="))
+	}
+	return truthOf(n.any(env, n.op))
+}
+
+func (n compare) any(env env, op string) bool {
+	for _, value := range n.values(env) {
+		if n.number {
+			parsed, _ := strconv.ParseInt(value, 10, 64)
+			if compareNumbers(parsed, op, n.parsed) {
+				return true
+			}
+			continue
+		}
+		switch op {
+		case "=":
+			if strings.EqualFold(value, n.value) {
+				return true
+			}
+		case "~":
+			if strings.Contains(strings.ToLower(value), strings.ToLower(n.value)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func compareNumbers(a int64, op string, b int64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+type field struct {
+	number bool
+	kill   bool
+	values func(env env) []string
+}
+
+var _fields = map[string]field{
+	"map":     {values: func(env env) []string { return []string{env.match.MapName()} }},
+	"source":  {values: func(env env) []string { return []string{env.source} }},
+	"index":   {number: true, values: func(env env) []string { return []string{strconv.Itoa(env.index)} }},
+	"kills":   {number: true, values: func(env env) []string { return []string{strconv.Itoa(len(env.match.Events))} }},
+	"players": {number: true, values: func(env env) []string { return []string{strconv.Itoa(len(env.match.Players))} }},
+	"start": {number: true, values: func(env env) []string {
+		return []string{strconv.FormatInt(int64(env.match.StartTime), 10)}
+	}},
+	"player": {values: func(env env) []string {
+		names := []string{}
+		for _, player := range env.match.Players {
+			names = append(names, player.Name)
+		}
+		return names
+	}},
+	"killer": {kill: true, values: func(env env) []string {
+		return []string{playerName(env.match, env.kill.KillerID, env.kill.Time)}
+	}},
+	"victim": {kill: true, values: func(env env) []string {
+		return []string{playerName(env.match, env.kill.VictimID, env.kill.Time)}
+	}},
+	"weapon": {kill: true, values: func(env env) []string {
+		return []string{output.Weapon(*env.kill)}
+	}},
+}
+
+// playerName returns the name of the player on the client slot id at a
+// time, World for the world and an empty name when no player was on it.
+func playerName(match parser.Match, id int, at time.Duration) string {
+	if id == _worldID {
+		return World
+	}
+	if index := match.PlayerAt(id, at); index != -1 {
+		return match.Players[index].Name
+	}
+	return ""
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+func tokenize(expr string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '~' || r == '=':
+			tokens = append(tokens, token{text: string(r)})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{text: string(runes[i : i+2])})
+				i += 2
+				continue
+			}
+			if r == '!' {
+				return nil, errors.New("Expected = after ! in query")
+			}
+			tokens = append(tokens, token{text: string(r)})
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, errors.New("Unterminated string in query")
+			}
+			tokens = append(tokens, token{text: string(runes[i+1 : end]), quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()~=!<>"'`, runes[end]) {
+				end++
+			}
+			tokens = append(tokens, token{text: string(runes[i:end])})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type compiler struct {
+	tokens []token
+	pos    int
+	kills  bool
+}
+
+func (c *compiler) next() (token, bool) {
+	if c.pos == len(c.tokens) {
+		return token{}, false
+	}
+	c.pos++
+	return c.tokens[c.pos-1], true
+}
+
+func (c *compiler) keyword(word string) bool {
+	if c.pos < len(c.tokens) && !c.tokens[c.pos].quoted && strings.EqualFold(c.tokens[c.pos].text, word) {
+		c.pos++
+		return true
+	}
+	return false
+}
+
+func (c *compiler) or() (node, error) {
+	left, err := c.and()
+	if err != nil {
+		return nil, err
+	}
+	for c.keyword("or") {
+		right, err := c.and()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+func (c *compiler) and() (node, error) {
+	left, err := c.unary()
+	if err != nil {
+		return nil, err
+	}
+	for c.keyword("and") {
+		right, err := c.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+	return left, nil
+}
+
+func (c *compiler) unary() (node, error) {
+	if c.keyword("not") {
+		n, err := c.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{n}, nil
+	}
+	if c.keyword("(") {
+		n, err := c.or()
+		if err != nil {
+			return nil, err
+		}
+		if !c.keyword(")") {
+			return nil, errors.New("Expected ) in query")
+		}
+		return n, nil
+	}
+	return c.compare()
+}
+
+func (c *compiler) compare() (node, error) {
+	name, ok := c.next()
+	if !ok {
+		return nil, errors.New("Unexpected end of query")
+	}
+	f, ok := _fields[strings.ToLower(name.text)]
+	if !ok || name.quoted {
+		return nil, fmt.Errorf("Unknown field %q in query", name.text)
+	}
+	op, ok := c.next()
+	if !ok || op.quoted || !strings.Contains(" = != > >= < <= ~ ", " "+op.text+" ") {
+		return nil, fmt.Errorf("Expected an operator after %q in query", name.text)
+	}
+	value, ok := c.next()
+	if !ok {
+		return nil, fmt.Errorf("Expected a value after %q in query", name.text+" "+op.text)
+	}
+	n := compare{values: f.values, kill: f.kill, number: f.number, op: op.text, value: value.text}
+	switch {
+	case f.number && op.text == "~":
+		return nil, fmt.Errorf("Operator ~ can't be used with %s", name.text)
+	case !f.number && op.text != "=" && op.text != "!=" && op.text != "~":
+		return nil, fmt.Errorf("Operator %s can't be used with %s", op.text, name.text)
+	case strings.EqualFold(name.text, "start"):
+		start, err := parser.ParseTimestamp(value.text)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid time %q in query", value.text)
+		}
+		n.parsed = int64(start)
+	case f.number:
+		parsed, err := strconv.ParseInt(value.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid number %q in query", value.text)
+		}
+		n.parsed = parsed
+	case strings.EqualFold(name.text, "weapon") && op.text != "~":
+		n.value = strings.ToUpper(value.text)
+		if !strings.Contains(n.value, "MOD_") {
+			n.value = "MOD_" + n.value
+		}
+	}
+	if f.kill {
+		c.kills = true
+	}
+	return n, nil
+}
@@ -0,0 +1,257 @@
+package query_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/query"
+	"github.com/stretchr/testify/assert"
+)
+
+var _match = parser.Match{
+	StartTime: 20 * time.Minute,
+	Settings:  map[string]string{"mapname": "q3dm17"},
+	Players: []parser.Player{
+		{ID: 2, Name: "Isgalamido"},
+		{ID: 3, Name: "Mocinha"},
+	},
+	Events: []parser.Kill{
+		{KillerID: 1022, VictimID: 2, MeanOfDeath: 22},
+		{KillerID: 2, VictimID: 3, MeanOfDeath: 10},
+		{KillerID: 3, VictimID: 2, MeanOfDeath: 6},
+		{KillerID: 2, VictimID: 3, MeanOfDeath: 7},
+	},
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		ok     bool
+		events []parser.Kill
+	}{
+		{
+			name:   "Map",
+			expr:   "map = q3dm17",
+			ok:     true,
+			events: _match.Events,
+		},
+		{
+			name: "Different map",
+			expr: "map = q3dm6",
+			ok:   false,
+		},
+		{
+			name: "Total of kills",
+			expr: "map = q3dm17 and kills > 50",
+			ok:   false,
+		},
+		{
+			name:   "Total of kills or another condition",
+			expr:   "kills > 50 or players >= 2",
+			ok:     true,
+			events: _match.Events,
+		},
+		{
+			name:   "Player that played",
+			expr:   `player = "mocinha"`,
+			ok:     true,
+			events: _match.Events,
+		},
+		{
+			name: "Player that didn't play",
+			expr: "player != Mocinha",
+			ok:   false,
+		},
+		{
+			name:   "Part of a player name",
+			expr:   "player ~ gala",
+			ok:     true,
+			events: _match.Events,
+		},
+		{
+			name:   "Time range",
+			expr:   "start >= 10:00 and start < 30:00",
+			ok:     true,
+			events: _match.Events,
+		},
+		{
+			name: "Time range not started yet",
+			expr: "not (start >= 10:00 and start < 30:00)",
+			ok:   false,
+		},
+		{
+			name:   "Source and index",
+			expr:   "source = games.log and index = 2",
+			ok:     true,
+			events: _match.Events,
+		},
+		{
+			name:   "Kills of a player with a weapon",
+			expr:   "killer = Isgalamido and weapon = railgun",
+			ok:     true,
+			events: []parser.Kill{{KillerID: 2, VictimID: 3, MeanOfDeath: 10}},
+		},
+		{
+			name: "Kills by the world",
+			expr: `killer = "<world>"`,
+			ok:   true,
+			events: []parser.Kill{
+				{KillerID: 1022, VictimID: 2, MeanOfDeath: 22},
+			},
+		},
+		{
+			name: "Kills by part of the weapon name",
+			expr: "map = q3dm17 and weapon ~ ROCKET",
+			ok:   true,
+			events: []parser.Kill{
+				{KillerID: 3, VictimID: 2, MeanOfDeath: 6},
+				{KillerID: 2, VictimID: 3, MeanOfDeath: 7},
+			},
+		},
+		{
+			name: "Match without the kills",
+			expr: "victim = Mocinha and weapon = MOD_BFG",
+			ok:   false,
+		},
+		{
+			name:   "Match satisfying the query without its kills",
+			expr:   "map = q3dm17 or killer = Mocinha",
+			ok:     true,
+			events: _match.Events,
+		},
+		{
+			name:   "Match not satisfying the query without its kills",
+			expr:   "map = q3dm6 or killer = Mocinha",
+			ok:     true,
+			events: []parser.Kill{{KillerID: 3, VictimID: 2, MeanOfDeath: 6}},
+		},
+		{
+			name: "Match satisfying the query only by kills it doesn't have",
+			expr: "not (map = q3dm17) or weapon = MOD_BFG",
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := query.Parse(tt.expr)
+			assert.Nil(t, err)
+			match, ok := q.Match("games.log", 2, _match)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.events, match.Events)
+				assert.Equal(t, _match.Players, match.Players)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		err  error
+	}{
+		{
+			name: "Unknown field",
+			expr: "mapname = q3dm17",
+			err:  errors.New(`Unknown field "mapname" in query`),
+		},
+		{
+			name: "Missing operator",
+			expr: "map q3dm17",
+			err:  errors.New(`Expected an operator after "map" in query`),
+		},
+		{
+			name: "Missing value",
+			expr: "kills >",
+			err:  errors.New(`Expected a value after "kills >" in query`),
+		},
+		{
+			name: "Invalid number",
+			expr: "kills > many",
+			err:  errors.New(`Invalid number "many" in query`),
+		},
+		{
+			name: "Invalid time",
+			expr: "start > noon",
+			err:  errors.New(`Invalid time "noon" in query`),
+		},
+		{
+			name: "Ordered names",
+			expr: "player > Mocinha",
+			err:  errors.New("Operator > can't be used with player"),
+		},
+		{
+			name: "Unclosed parenthesis",
+			expr: "(map = q3dm17 or kills > 1",
+			err:  errors.New("Expected ) in query"),
+		},
+		{
+			name: "Unterminated string",
+			expr: `player = "Mocinha`,
+			err:  errors.New("Unterminated string in query"),
+		},
+		{
+			name: "Trailing tokens",
+			expr: "map = q3dm17 kills",
+			err:  errors.New(`Unexpected "kills" in query`),
+		},
+		{
+			name: "Empty query",
+			expr: "",
+			err:  errors.New("Unexpected end of query"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := query.Parse(tt.expr)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestMatchWithoutKills(t *testing.T) {
+	match := _match
+	match.Events = []parser.Kill{}
+	q, err := query.Parse(`map = q3dm17 or killer = "Isgalamido"`)
+	assert.Nil(t, err)
+	got, ok := q.Match("games.log", 1, match)
+	assert.True(t, ok)
+	assert.Equal(t, []parser.Kill{}, got.Events)
+
+	q, err = query.Parse(`killer = "Isgalamido"`)
+	assert.Nil(t, err)
+	_, ok = q.Match("games.log", 1, match)
+	assert.False(t, ok)
+}
+
+func TestMatchUnknownSlot(t *testing.T) {
+	match := _match
+	match.Events = []parser.Kill{
+		{KillerID: 5, VictimID: 2, MeanOfDeath: 10},
+		{KillerID: 1022, VictimID: 3, MeanOfDeath: 22},
+	}
+	q, err := query.Parse(`killer = "<world>"`)
+	assert.Nil(t, err)
+	got, ok := q.Match("games.log", 1, match)
+	assert.True(t, ok)
+	assert.Equal(t, []parser.Kill{{KillerID: 1022, VictimID: 3, MeanOfDeath: 22}}, got.Events)
+}
+
+func TestMatchWeaponName(t *testing.T) {
+	match := _match
+	match.Events = []parser.Kill{
+		{KillerID: 2, VictimID: 3, MeanOfDeath: 19, Weapon: "UT_MOD_LR300"},
+		{KillerID: 3, VictimID: 2, MeanOfDeath: 10},
+	}
+	q, err := query.Parse("weapon = ut_mod_lr300 or weapon = railgun")
+	assert.Nil(t, err)
+	got, ok := q.Match("games.log", 1, match)
+	assert.True(t, ok)
+	assert.Equal(t, match.Events, got.Events)
+}
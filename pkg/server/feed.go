@@ -0,0 +1,182 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+)
+
+const (
+	_feedBuffer        int           = 64
+	_keepAliveInterval time.Duration = 15 * time.Second
+	_worldID           int           = 1022
+	_worldName         string        = "<world>"
+)
+
+// FeedEvent is an event pushed to the subscribers of the live feed. Only
+// the fields that make sense for its Type are set.
+type FeedEvent struct {
+	// Type is one of match_start, match_end, connect, disconnect or kill.
+	Type   string `json:"type"`
+	Source string `json:"source"`
+	Match  int    `json:"match"`
+	Map    string `json:"map"`
+	Time   string `json:"time"`
+	Player string `json:"player,omitempty"`
+	Killer string `json:"killer,omitempty"`
+	Victim string `json:"victim,omitempty"`
+	Weapon string `json:"weapon,omitempty"`
+}
+
+// NewFeedEvent converts a parser.Event of the source log file to a
+// FeedEvent, resolving the player names and the mean of death. It
+// returns false for events that are not part of the feed.
+func NewFeedEvent(source string, event parser.Event) (FeedEvent, bool) {
+	feedEvent := FeedEvent{
+		Source: source,
+		Match:  event.Index,
+		Map:    event.Match.MapName(),
+		Time:   output.FormatTimestamp(event.Time),
+	}
+	switch event.Name {
+	case "InitGame":
+		feedEvent.Type = "match_start"
+	case "ShutdownGame":
+		feedEvent.Type = "match_end"
+	case "ClientBegin", "ClientDisconnect":
+		feedEvent.Type = "connect"
+		if event.Name == "ClientDisconnect" {
+			feedEvent.Type = "disconnect"
+		}
+		playerID, err := strconv.Atoi(strings.TrimSpace(event.Payload))
+		if err != nil {
+			return FeedEvent{}, false
+		}
+		feedEvent.Player = playerName(event.Match, playerID, event.Time)
+	case "Kill":
+		var killerID, victimID, meanOfDeath int
+		if _, err := fmt.Sscanf(event.Payload, "%d %d %d:", &killerID, &victimID, &meanOfDeath); err != nil {
+			return FeedEvent{}, false
+		}
+		feedEvent.Type = "kill"
+		feedEvent.Killer = playerName(event.Match, killerID, event.Time)
+		feedEvent.Victim = playerName(event.Match, victimID, event.Time)
+		feedEvent.Weapon = output.MeanOfDeath(meanOfDeath)
+		// The match already has the kill, with the weapon logged by
+		// dialects that number them differently.
+		if n := len(event.Match.Events); n > 0 {
+			feedEvent.Weapon = output.Weapon(event.Match.Events[n-1])
+		}
+	default:
+		return FeedEvent{}, false
+	}
+	return feedEvent, true
+}
+
+func playerName(match parser.Match, id int, at time.Duration) string {
+	if id == _worldID {
+		return _worldName
+	}
+	if index := match.PlayerAt(id, at); index != -1 {
+		return match.Players[index].Name
+	}
+	return ""
+}
+
+// Feed pushes FeedEvents to every subscribed client as Server-Sent Events.
+// Clients too slow to keep up lose events instead of slowing the parser.
+type Feed struct {
+	mu          sync.Mutex
+	subscribers map[chan FeedEvent]struct{}
+}
+
+// NewFeed returns a Feed with no subscribers.
+func NewFeed() *Feed {
+	return &Feed{
+		subscribers: map[chan FeedEvent]struct{}{},
+	}
+}
+
+// Publish sends event to every subscriber.
+func (f *Feed) Publish(event FeedEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for subscriber := range f.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+func (f *Feed) subscribe() chan FeedEvent {
+	subscriber := make(chan FeedEvent, _feedBuffer)
+	f.mu.Lock()
+	f.subscribers[subscriber] = struct{}{}
+	f.mu.Unlock()
+	return subscriber
+}
+
+func (f *Feed) unsubscribe(subscriber chan FeedEvent) {
+	f.mu.Lock()
+	delete(f.subscribers, subscriber)
+	f.mu.Unlock()
+}
+
+// ServeHTTP streams the events to the client until it disconnects. The
+// types and source query parameters filter which events are sent, like
+// ?types=kill,connect&source=games.log.
+func (f *Feed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	types := map[string]bool{}
+	for _, value := range strings.Split(r.URL.Query().Get("types"), ",") {
+		if value != "" {
+			types[value] = true
+		}
+	}
+	source := r.URL.Query().Get("source")
+
+	subscriber := f.subscribe()
+	defer f.unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(_keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-subscriber:
+			if len(types) > 0 && !types[event.Type] {
+				continue
+			}
+			if source != "" && event.Source != source {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		flusher.Flush()
+	}
+}
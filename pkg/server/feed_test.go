@@ -0,0 +1,118 @@
+package server_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+var feedMatch = parser.Match{
+	Players: []parser.Player{
+		{
+			ID:   2,
+			Name: "Isgalamido",
+		},
+		{
+			ID:   3,
+			Name: "Mocinha",
+		},
+	},
+	Settings: map[string]string{"mapname": "q3dm17"},
+}
+
+func TestNewFeedEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event parser.Event
+		want  server.FeedEvent
+		ok    bool
+	}{
+		{
+			name:  "Match start",
+			event: parser.Event{Name: "InitGame", Index: 1, Match: feedMatch},
+			want:  server.FeedEvent{Type: "match_start", Source: "games.log", Match: 1, Map: "q3dm17", Time: "0:00"},
+			ok:    true,
+		},
+		{
+			name:  "Match end",
+			event: parser.Event{Name: "ShutdownGame", Time: 90 * time.Second, Index: 1, Match: feedMatch},
+			want:  server.FeedEvent{Type: "match_end", Source: "games.log", Match: 1, Map: "q3dm17", Time: "1:30"},
+			ok:    true,
+		},
+		{
+			name:  "Connect",
+			event: parser.Event{Name: "ClientBegin", Payload: "2", Index: 1, Match: feedMatch},
+			want:  server.FeedEvent{Type: "connect", Source: "games.log", Match: 1, Map: "q3dm17", Time: "0:00", Player: "Isgalamido"},
+			ok:    true,
+		},
+		{
+			name:  "Disconnect",
+			event: parser.Event{Name: "ClientDisconnect", Payload: "3", Index: 1, Match: feedMatch},
+			want:  server.FeedEvent{Type: "disconnect", Source: "games.log", Match: 1, Map: "q3dm17", Time: "0:00", Player: "Mocinha"},
+			ok:    true,
+		},
+		{
+			name:  "Kill by a player",
+			event: parser.Event{Name: "Kill", Payload: "2 3 10: Isgalamido killed Mocinha by MOD_RAILGUN", Index: 1, Match: feedMatch},
+			want:  server.FeedEvent{Type: "kill", Source: "games.log", Match: 1, Map: "q3dm17", Time: "0:00", Killer: "Isgalamido", Victim: "Mocinha", Weapon: "MOD_RAILGUN"},
+			ok:    true,
+		},
+		{
+			name:  "Kill by the world",
+			event: parser.Event{Name: "Kill", Payload: "1022 2 22: <world> killed Isgalamido by MOD_TRIGGER_HURT", Index: 1, Match: feedMatch},
+			want:  server.FeedEvent{Type: "kill", Source: "games.log", Match: 1, Map: "q3dm17", Time: "0:00", Killer: "<world>", Victim: "Isgalamido", Weapon: "MOD_TRIGGER_HURT"},
+			ok:    true,
+		},
+		{
+			name:  "Event out of the feed",
+			event: parser.Event{Name: "Item", Payload: "2 weapon_rocketlauncher", Index: 1, Match: feedMatch},
+			ok:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := server.NewFeedEvent("games.log", tt.event)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFeed(t *testing.T) {
+	s := server.New()
+	httpServer := httptest.NewServer(s.Handler())
+	defer httpServer.Close()
+
+	response, err := http.Get(httpServer.URL + "/api/feed?types=kill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	s.Publish("games.log", parser.Event{Name: "ClientBegin", Payload: "2", Index: 1, Match: feedMatch})
+	s.Publish("games.log", parser.Event{Name: "Kill", Payload: "2 3 10: Isgalamido killed Mocinha by MOD_RAILGUN", Index: 1, Match: feedMatch})
+
+	reader := bufio.NewReader(response.Body)
+	lines := []string{}
+	for len(lines) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	assert.Equal(t, []string{
+		"event: kill",
+		`data: {"type":"kill","source":"games.log","match":1,"map":"q3dm17","time":"0:00","killer":"Isgalamido","victim":"Mocinha","weapon":"MOD_RAILGUN"}`,
+	}, lines)
+}
@@ -0,0 +1,347 @@
+// Package server exposes parsed Quake 3 Arena matches through a JSON API.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/reesilva/quake-log/pkg/identity"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+)
+
+const (
+	_defaultLimit int = 50
+	_maxLimit     int = 500
+)
+
+// Page is a slice of a listing along with how many items it has in total.
+type Page struct {
+	Total  int         `json:"total"`
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+	Items  interface{} `json:"items"`
+}
+
+// WeaponStats is how many kills were made with a mean of death.
+type WeaponStats struct {
+	Weapon string `json:"weapon"`
+	Kills  int    `json:"kills"`
+}
+
+type record struct {
+	match parser.Match
+	entry output.MatchEntry
+}
+
+// Server keeps the matches parsed from one or more log files and serves
+// them. It is safe to add matches while the API is being served.
+type Server struct {
+	// Identities, when set, renames the players of the matches and of the
+	// live feed to their canonical names.
+	Identities *identity.Identities
+	// ExcludeBots removes the bots of the matches, and the kills they made
+	// or suffered, from the API.
+	ExcludeBots bool
+
+	mu      sync.RWMutex
+	records []record
+	bySlot  map[string]int
+	ids     map[string]*output.MatchIDs
+	feed    *Feed
+}
+
+// New returns an empty Server.
+func New() *Server {
+	return &Server{
+		records: []record{},
+		bySlot:  map[string]int{},
+		ids:     map[string]*output.MatchIDs{},
+		feed:    NewFeed(),
+	}
+}
+
+// Put adds the match found at the 1-based index of the source log file, or
+// replaces it if it was already added, so a match being played can be
+// updated while it goes on. The match is copied, so the parser can keep
+// changing it while the API is being served. Its ID changes along with it,
+// so a match being played is found by its source and index instead.
+func (s *Server) Put(source string, index int, match parser.Match) {
+	match = match.Copy()
+	parsed := match
+	if s.ExcludeBots {
+		match = match.WithoutBots()
+	}
+	if s.Identities != nil {
+		match = s.Identities.Apply(match)
+	}
+	r := record{
+		match: match,
+		entry: output.CreateMatchEntry(index, match, true),
+	}
+	r.entry.Source = source
+	slot := source + "#" + strconv.Itoa(index)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ids[source] == nil {
+		s.ids[source] = &output.MatchIDs{}
+	}
+	r.entry.ID = s.ids[source].ID(index, parsed)
+	if i, ok := s.bySlot[slot]; ok {
+		s.records[i] = r
+		return
+	}
+	s.bySlot[slot] = len(s.records)
+	s.records = append(s.records, r)
+}
+
+// Publish pushes an event of the source log file to the live feed.
+func (s *Server) Publish(source string, event parser.Event) {
+	if s.Identities != nil {
+		event.Match = s.Identities.Apply(event.Match)
+	}
+	if feedEvent, ok := NewFeedEvent(source, event); ok {
+		s.feed.Publish(feedEvent)
+	}
+}
+
+// Handler returns the http.Handler of the API:
+//
+//	GET /api/matches              ?map=&player=&source=&index=&min_kills=&offset=&limit=
+//	GET /api/matches/{id}
+//	GET /api/players              ?name=&offset=&limit=
+//	GET /api/players/{name}
+//	GET /api/leaderboard          ?by=kills|deaths|matches&offset=&limit=
+//	GET /api/weapons
+//	GET /api/feed                 ?types=&source=    (Server-Sent Events)
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/matches", s.listMatches)
+	mux.HandleFunc("/api/matches/", s.getMatch)
+	mux.HandleFunc("/api/players", s.listPlayers)
+	mux.HandleFunc("/api/players/", s.getPlayer)
+	mux.HandleFunc("/api/leaderboard", s.leaderboard)
+	mux.HandleFunc("/api/weapons", s.weapons)
+	mux.Handle("/api/feed", s.feed)
+	return mux
+}
+
+func (s *Server) listMatches(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	minKills, index := 0, 0
+	if value := query.Get("min_kills"); value != "" {
+		var err error
+		if minKills, err = strconv.Atoi(value); err != nil {
+			writeError(w, http.StatusBadRequest, "min_kills must be a number")
+			return
+		}
+	}
+	if value := query.Get("index"); value != "" {
+		var err error
+		if index, err = strconv.Atoi(value); err != nil || index < 1 {
+			writeError(w, http.StatusBadRequest, "index must be a number greater than zero")
+			return
+		}
+	}
+	s.mu.RLock()
+	matches := []output.MatchEntry{}
+	for _, r := range s.records {
+		if value := query.Get("map"); value != "" && r.entry.Map != value {
+			continue
+		}
+		if value := query.Get("source"); value != "" && r.entry.Source != value {
+			continue
+		}
+		if index != 0 && r.entry.Index != index {
+			continue
+		}
+		if value := query.Get("player"); value != "" && !contains(r.entry.Players, value) {
+			continue
+		}
+		if r.entry.TotalKills < minKills {
+			continue
+		}
+		matches = append(matches, r.entry)
+	}
+	s.mu.RUnlock()
+
+	offset, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+	start, end := bounds(len(matches), offset, limit)
+	writeJSON(w, http.StatusOK, Page{
+		Total:  len(matches),
+		Offset: offset,
+		Limit:  limit,
+		Items:  matches[start:end],
+	})
+}
+
+func (s *Server) getMatch(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/matches/")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.records {
+		if r.entry.ID == id {
+			writeJSON(w, http.StatusOK, r.entry)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "match not found")
+}
+
+func (s *Server) playerStats() []output.PlayerStats {
+	s.mu.RLock()
+	matches := make([]parser.Match, 0, len(s.records))
+	for _, r := range s.records {
+		matches = append(matches, r.match)
+	}
+	s.mu.RUnlock()
+
+	stats := []output.PlayerStats{}
+	for _, stat := range output.CreatePlayerStats(matches) {
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+func (s *Server) listPlayers(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(r.URL.Query().Get("name"))
+	players := []output.PlayerStats{}
+	for _, stat := range s.playerStats() {
+		if strings.Contains(strings.ToLower(stat.Name), name) {
+			players = append(players, stat)
+		}
+	}
+	offset, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+	start, end := bounds(len(players), offset, limit)
+	writeJSON(w, http.StatusOK, Page{
+		Total:  len(players),
+		Offset: offset,
+		Limit:  limit,
+		Items:  players[start:end],
+	})
+}
+
+func (s *Server) getPlayer(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/players/")
+	for _, stat := range s.playerStats() {
+		if stat.Name == name {
+			writeJSON(w, http.StatusOK, stat)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "player not found")
+}
+
+func (s *Server) leaderboard(w http.ResponseWriter, r *http.Request) {
+	var value func(output.PlayerStats) int
+	switch by := r.URL.Query().Get("by"); by {
+	case "", "kills":
+		value = func(stat output.PlayerStats) int { return stat.Kills }
+	case "deaths":
+		value = func(stat output.PlayerStats) int { return stat.Deaths }
+	case "matches":
+		value = func(stat output.PlayerStats) int { return stat.Matches }
+	default:
+		writeError(w, http.StatusBadRequest, "by must be kills, deaths or matches")
+		return
+	}
+	players := s.playerStats()
+	sort.SliceStable(players, func(i, j int) bool { return value(players[i]) > value(players[j]) })
+	offset, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+	start, end := bounds(len(players), offset, limit)
+	writeJSON(w, http.StatusOK, Page{
+		Total:  len(players),
+		Offset: offset,
+		Limit:  limit,
+		Items:  players[start:end],
+	})
+}
+
+func (s *Server) weapons(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	matches := make([]parser.Match, 0, len(s.records))
+	for _, r := range s.records {
+		matches = append(matches, r.match)
+	}
+	s.mu.RUnlock()
+
+	weapons := []WeaponStats{}
+	for weapon, kills := range output.CreateWeaponStats(matches) {
+		weapons = append(weapons, WeaponStats{Weapon: weapon, Kills: kills})
+	}
+	sort.Slice(weapons, func(i, j int) bool {
+		if weapons[i].Kills != weapons[j].Kills {
+			return weapons[i].Kills > weapons[j].Kills
+		}
+		return weapons[i].Weapon < weapons[j].Weapon
+	})
+	writeJSON(w, http.StatusOK, weapons)
+}
+
+// pagination reads offset and limit from the query string, writing a
+// bad request response and returning false when they are invalid.
+func pagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	query := r.URL.Query()
+	offset, limit := 0, _defaultLimit
+	var err error
+	if value := query.Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, "offset must be a positive number")
+			return 0, 0, false
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a number greater than zero")
+			return 0, 0, false
+		}
+	}
+	if limit > _maxLimit {
+		limit = _maxLimit
+	}
+	return offset, limit, true
+}
+
+func bounds(total, offset, limit int) (int, int) {
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,285 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reesilva/quake-log/pkg/identity"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func newServer() *server.Server {
+	s := server.New()
+	s.Put("games.log", 1, parser.Match{
+		Players: []parser.Player{
+			{
+				ID:   2,
+				Name: "Isgalamido",
+			},
+			{
+				ID:   3,
+				Name: "Mocinha",
+			},
+		},
+		Events: []parser.Kill{
+			{
+				KillerID:    2,
+				VictimID:    3,
+				MeanOfDeath: 10,
+			},
+			{
+				KillerID:    2,
+				VictimID:    3,
+				MeanOfDeath: 10,
+			},
+			{
+				KillerID:    1022,
+				VictimID:    2,
+				MeanOfDeath: 22,
+			},
+		},
+		Settings: map[string]string{"mapname": "q3dm17"},
+	})
+	s.Put("games.log", 2, parser.Match{
+		Players: []parser.Player{
+			{
+				ID:   2,
+				Name: "Mocinha",
+			},
+		},
+		Events:   []parser.Kill{},
+		Settings: map[string]string{"mapname": "q3dm6"},
+	})
+	return s
+}
+
+func get(t *testing.T, s *server.Server, url string, body interface{}) int {
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+	if body != nil {
+		if err := json.Unmarshal(recorder.Body.Bytes(), body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return recorder.Code
+}
+
+func TestListMatches(t *testing.T) {
+	s := newServer()
+	tests := []struct {
+		name    string
+		url     string
+		status  int
+		total   int
+		indexes []int
+	}{
+		{name: "All matches", url: "/api/matches", status: 200, total: 2, indexes: []int{1, 2}},
+		{name: "By map", url: "/api/matches?map=q3dm6", status: 200, total: 1, indexes: []int{2}},
+		{name: "By player", url: "/api/matches?player=Isgalamido", status: 200, total: 1, indexes: []int{1}},
+		{name: "By kills", url: "/api/matches?min_kills=1", status: 200, total: 1, indexes: []int{1}},
+		{name: "By source", url: "/api/matches?source=other.log", status: 200, total: 0, indexes: []int{}},
+		{name: "By source and index", url: "/api/matches?source=games.log&index=2", status: 200, total: 1, indexes: []int{2}},
+		{name: "Paginated", url: "/api/matches?offset=1&limit=1", status: 200, total: 2, indexes: []int{2}},
+		{name: "Offset after the end", url: "/api/matches?offset=10", status: 200, total: 2, indexes: []int{}},
+		{name: "Invalid limit", url: "/api/matches?limit=0", status: 400},
+		{name: "Invalid kills", url: "/api/matches?min_kills=a", status: 400},
+		{name: "Invalid index", url: "/api/matches?index=0", status: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := struct {
+				Total int                 `json:"total"`
+				Items []output.MatchEntry `json:"items"`
+			}{}
+			status := get(t, s, tt.url, &page)
+			assert.Equal(t, tt.status, status)
+			if tt.status != 200 {
+				return
+			}
+			assert.Equal(t, tt.total, page.Total)
+			indexes := []int{}
+			for _, match := range page.Items {
+				indexes = append(indexes, match.Index)
+			}
+			assert.Equal(t, tt.indexes, indexes)
+		})
+	}
+}
+
+func TestGetMatch(t *testing.T) {
+	s := newServer()
+	page := struct {
+		Items []output.MatchEntry `json:"items"`
+	}{}
+	get(t, s, "/api/matches?limit=1", &page)
+
+	match := output.MatchEntry{}
+	assert.Equal(t, 200, get(t, s, "/api/matches/"+page.Items[0].ID, &match))
+	assert.Equal(t, "games.log", match.Source)
+	assert.Equal(t, 3, match.TotalKills)
+	assert.Equal(t, map[string]int{"MOD_RAILGUN": 2, "MOD_TRIGGER_HURT": 1}, match.KillsByMeans)
+
+	assert.Equal(t, 404, get(t, s, "/api/matches/unknown", nil))
+}
+
+func TestPutReplacesMatch(t *testing.T) {
+	s := newServer()
+	s.Put("games.log", 2, parser.Match{
+		Players:  []parser.Player{},
+		Events:   []parser.Kill{},
+		Settings: map[string]string{"mapname": "q3dm7"},
+	})
+	page := struct {
+		Total int                 `json:"total"`
+		Items []output.MatchEntry `json:"items"`
+	}{}
+	get(t, s, "/api/matches", &page)
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, "q3dm7", page.Items[1].Map)
+}
+
+func TestPutWhileServing(t *testing.T) {
+	s := server.New()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stream := parser.Stream{}
+		lines := []string{}
+		for i := 0; i < 20; i++ {
+			lines = append(lines, `  0:00 InitGame: \mapname\q3dm17`)
+			for id := 2; id < 40; id++ {
+				lines = append(lines,
+					fmt.Sprintf(" 0:%02d ClientConnect: %d", id, id),
+					fmt.Sprintf(` 0:%02d ClientUserinfoChanged: %d n\Player%d\t\0`, id, id, id),
+					fmt.Sprintf(" 0:%02d Kill: %d 2 10: killed by MOD_RAILGUN", id, id),
+				)
+			}
+		}
+		for _, line := range lines {
+			assert.NoError(t, stream.ParseLine(line))
+			if index, match, ok := stream.Current(); ok {
+				s.Put("games.log", index, match)
+			}
+		}
+		assert.NoError(t, stream.Close())
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		assert.Equal(t, 200, get(t, s, "/api/players", nil))
+		assert.Equal(t, 200, get(t, s, "/api/weapons", nil))
+	}
+	page := struct {
+		Items []output.MatchEntry `json:"items"`
+	}{}
+	assert.Equal(t, 200, get(t, s, "/api/matches?source=games.log&index=20", &page))
+	assert.Equal(t, 38, page.Items[0].TotalKills)
+}
+
+func TestPlayers(t *testing.T) {
+	s := newServer()
+	page := struct {
+		Total int                  `json:"total"`
+		Items []output.PlayerStats `json:"items"`
+	}{}
+	assert.Equal(t, 200, get(t, s, "/api/players?name=moc", &page))
+	assert.Equal(t, []output.PlayerStats{{Name: "Mocinha", Matches: 2, Kills: 0, Deaths: 2}}, page.Items)
+
+	player := output.PlayerStats{}
+	assert.Equal(t, 200, get(t, s, "/api/players/Isgalamido", &player))
+	assert.Equal(t, output.PlayerStats{Name: "Isgalamido", Matches: 1, Kills: 2, Deaths: 1}, player)
+	assert.Equal(t, 404, get(t, s, "/api/players/Nobody", nil))
+}
+
+func TestPlayersIdentities(t *testing.T) {
+	ids, err := identity.New([]identity.Identity{{Name: "Isgalamido", Aliases: []string{"Mocinha"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New()
+	s.Identities = ids
+	match := parser.Match{
+		Players:  []parser.Player{{ID: 2, Name: "Mocinha"}},
+		Events:   []parser.Kill{},
+		Settings: map[string]string{"mapname": "q3dm6"},
+	}
+	s.Put("games.log", 1, match)
+
+	player := output.PlayerStats{}
+	assert.Equal(t, 200, get(t, s, "/api/players/Isgalamido", &player))
+	assert.Equal(t, output.PlayerStats{Name: "Isgalamido", Matches: 1}, player)
+	assert.Equal(t, 404, get(t, s, "/api/players/Mocinha", nil))
+	assert.Equal(t, 200, get(t, s, "/api/matches/"+output.MatchID(match), nil))
+}
+
+func TestPlayersExcludeBots(t *testing.T) {
+	s := server.New()
+	s.ExcludeBots = true
+	s.Put("games.log", 1, parser.Match{
+		Players: []parser.Player{
+			{ID: 2, Name: "Isgalamido"},
+			{ID: 4, Name: "Sarge", IsBot: true},
+		},
+		Events: []parser.Kill{
+			{KillerID: 4, VictimID: 2, MeanOfDeath: 6},
+			{KillerID: 2, VictimID: 4, MeanOfDeath: 6},
+		},
+		Settings: map[string]string{"mapname": "q3dm17"},
+	})
+
+	player := output.PlayerStats{}
+	assert.Equal(t, 200, get(t, s, "/api/players/Isgalamido", &player))
+	assert.Equal(t, output.PlayerStats{Name: "Isgalamido", Matches: 1}, player)
+	assert.Equal(t, 404, get(t, s, "/api/players/Sarge", nil))
+}
+
+func TestLeaderboard(t *testing.T) {
+	s := newServer()
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		names  []string
+	}{
+		{name: "By kills", url: "/api/leaderboard", status: 200, names: []string{"Isgalamido", "Mocinha"}},
+		{name: "By deaths", url: "/api/leaderboard?by=deaths", status: 200, names: []string{"Mocinha", "Isgalamido"}},
+		{name: "By matches", url: "/api/leaderboard?by=matches&limit=1", status: 200, names: []string{"Mocinha"}},
+		{name: "Invalid field", url: "/api/leaderboard?by=score", status: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := struct {
+				Items []output.PlayerStats `json:"items"`
+			}{}
+			status := get(t, s, tt.url, &page)
+			assert.Equal(t, tt.status, status)
+			if tt.status != 200 {
+				return
+			}
+			names := []string{}
+			for _, player := range page.Items {
+				names = append(names, player.Name)
+			}
+			assert.Equal(t, tt.names, names)
+		})
+	}
+}
+
+func TestWeapons(t *testing.T) {
+	weapons := []server.WeaponStats{}
+	assert.Equal(t, 200, get(t, newServer(), "/api/weapons", &weapons))
+	assert.Equal(t, []server.WeaponStats{
+		{Weapon: "MOD_RAILGUN", Kills: 2},
+		{Weapon: "MOD_TRIGGER_HURT", Kills: 1},
+	}, weapons)
+}
@@ -0,0 +1,547 @@
+// Package store persists parsed Quake 3 Arena matches in a SQLite database.
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	// Registers the sqlite3 driver on database/sql.
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/reesilva/quake-log/pkg/checkpoint"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+)
+
+// _migrations are applied in order, each one only once, to bring the
+// database schema up to date. The number of migrations applied is kept
+// in the user_version pragma. New migrations must always be appended.
+var _migrations = []string{
+	`CREATE TABLE matches (
+		id          TEXT PRIMARY KEY,
+		source      TEXT NOT NULL,
+		position    INTEGER NOT NULL,
+		map         TEXT NOT NULL,
+		start_time  INTEGER NOT NULL,
+		ingested_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX matches_source_position ON matches (source, position);
+	CREATE TABLE settings (
+		match_id TEXT NOT NULL REFERENCES matches (id) ON DELETE CASCADE,
+		key      TEXT NOT NULL,
+		value    TEXT NOT NULL,
+		PRIMARY KEY (match_id, key)
+	);
+	CREATE TABLE players (
+		id   INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE
+	);
+	CREATE TABLE sessions (
+		match_id  TEXT NOT NULL REFERENCES matches (id) ON DELETE CASCADE,
+		position  INTEGER NOT NULL,
+		client_id INTEGER NOT NULL,
+		player_id INTEGER NOT NULL REFERENCES players (id),
+		PRIMARY KEY (match_id, position)
+	);
+	CREATE TABLE kills (
+		match_id       TEXT NOT NULL REFERENCES matches (id) ON DELETE CASCADE,
+		position       INTEGER NOT NULL,
+		killer_id      INTEGER NOT NULL,
+		victim_id      INTEGER NOT NULL,
+		mean_of_death  INTEGER NOT NULL,
+		PRIMARY KEY (match_id, position)
+	);`,
+	`CREATE TABLE checkpoints (
+		source     TEXT PRIMARY KEY,
+		checkpoint TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);`,
+	`ALTER TABLE sessions ADD COLUMN guid TEXT NOT NULL DEFAULT '';
+	ALTER TABLE sessions ADD COLUMN ip TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE sessions ADD COLUMN bot INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE kills ADD COLUMN weapon TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE kills ADD COLUMN time INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE matches ADD COLUMN end_time INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE sessions ADD COLUMN connected INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE sessions ADD COLUMN disconnected INTEGER NOT NULL DEFAULT 0;`,
+	`CREATE TABLE items (
+		match_id  TEXT NOT NULL REFERENCES matches (id) ON DELETE CASCADE,
+		position  INTEGER NOT NULL,
+		client_id INTEGER NOT NULL,
+		item      TEXT NOT NULL,
+		time      INTEGER NOT NULL,
+		PRIMARY KEY (match_id, position)
+	);
+	CREATE TABLE chat (
+		match_id TEXT NOT NULL REFERENCES matches (id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		name     TEXT NOT NULL,
+		message  TEXT NOT NULL,
+		team     INTEGER NOT NULL,
+		time     INTEGER NOT NULL,
+		PRIMARY KEY (match_id, position)
+	);`,
+	`CREATE TABLE hits (
+		match_id    TEXT NOT NULL REFERENCES matches (id) ON DELETE CASCADE,
+		position    INTEGER NOT NULL,
+		attacker_id INTEGER NOT NULL,
+		victim_id   INTEGER NOT NULL,
+		location    INTEGER NOT NULL,
+		weapon      INTEGER NOT NULL,
+		time        INTEGER NOT NULL,
+		PRIMARY KEY (match_id, position)
+	);
+	CREATE TABLE assists (
+		match_id     TEXT NOT NULL REFERENCES matches (id) ON DELETE CASCADE,
+		position     INTEGER NOT NULL,
+		assistant_id INTEGER NOT NULL,
+		killer_id    INTEGER NOT NULL,
+		victim_id    INTEGER NOT NULL,
+		time         INTEGER NOT NULL,
+		PRIMARY KEY (match_id, position)
+	);
+	CREATE TABLE player_stats (
+		match_id     TEXT NOT NULL REFERENCES matches (id) ON DELETE CASCADE,
+		position     INTEGER NOT NULL,
+		client_id    INTEGER NOT NULL,
+		name         TEXT NOT NULL,
+		steam_id     TEXT NOT NULL,
+		score        INTEGER NOT NULL,
+		kills        INTEGER NOT NULL,
+		deaths       INTEGER NOT NULL,
+		damage_dealt INTEGER NOT NULL,
+		damage_taken INTEGER NOT NULL,
+		play_time    INTEGER NOT NULL,
+		time         INTEGER NOT NULL,
+		PRIMARY KEY (match_id, position)
+	);
+	CREATE TABLE match_stats (
+		match_id     TEXT PRIMARY KEY REFERENCES matches (id) ON DELETE CASCADE,
+		exit_message TEXT NOT NULL,
+		length       INTEGER NOT NULL,
+		aborted      INTEGER NOT NULL,
+		red_score    INTEGER NOT NULL,
+		blue_score   INTEGER NOT NULL
+	);`,
+}
+
+// Store is a SQLite database of matches.
+type Store struct {
+	db *sql.DB
+	// ids gives the IDs of the matches saved from each source log file.
+	ids map[string]*output.MatchIDs
+}
+
+// Match is a match saved in the Store, along with where it came from.
+type Match struct {
+	ID     string
+	Source string
+	// Index is the 1-based position of the match in its source log file.
+	Index int
+	Match parser.Match
+}
+
+// Open opens, or creates, the SQLite database in path and migrates it
+// to the latest schema.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	s := &Store{db: db, ids: map[string]*output.MatchIDs{}}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(_migrations) {
+		return fmt.Errorf("Database schema version %d is newer than this version of quake-log", version)
+	}
+	for i := version; i < len(_migrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(_migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Migration %d failed: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveMatch saves the match found at the 1-based index of the source log
+// file. Matches are identified by output.MatchID, told apart from the
+// identical ones saved before them from the same source by
+// output.MatchIDs, so saving a match that is already in the database does
+// nothing and returns false.
+func (s *Store) SaveMatch(source string, index int, match parser.Match) (bool, error) {
+	if s.ids[source] == nil {
+		s.ids[source] = &output.MatchIDs{}
+	}
+	id := s.ids[source].ID(index, match)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT OR IGNORE INTO matches (id, source, position, map, start_time, end_time, ingested_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, source, index, match.MapName(), int64(match.StartTime), int64(match.EndTime), time.Now().UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	if inserted, err := result.RowsAffected(); err != nil || inserted == 0 {
+		return false, err
+	}
+
+	for key, value := range match.Settings {
+		if _, err := tx.Exec(`INSERT INTO settings (match_id, key, value) VALUES (?, ?, ?)`, id, key, value); err != nil {
+			return false, err
+		}
+	}
+	for position, player := range match.Players {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO players (name) VALUES (?)`, player.Name); err != nil {
+			return false, err
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO sessions (match_id, position, client_id, player_id, guid, ip, bot, connected, disconnected)
+			SELECT ?, ?, ?, id, ?, ?, ?, ?, ? FROM players WHERE name = ?`,
+			id, position, player.ID, player.GUID, player.IP, player.IsBot,
+			int64(player.Connected), int64(player.Disconnected), player.Name,
+		); err != nil {
+			return false, err
+		}
+	}
+	for position, kill := range match.Events {
+		if _, err := tx.Exec(
+			`INSERT INTO kills (match_id, position, killer_id, victim_id, mean_of_death, weapon, time)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, position, kill.KillerID, kill.VictimID, kill.MeanOfDeath, kill.Weapon, int64(kill.Time),
+		); err != nil {
+			return false, err
+		}
+	}
+	for position, item := range match.Items {
+		if _, err := tx.Exec(
+			`INSERT INTO items (match_id, position, client_id, item, time) VALUES (?, ?, ?, ?, ?)`,
+			id, position, item.PlayerID, item.Name, int64(item.Time),
+		); err != nil {
+			return false, err
+		}
+	}
+	for position, message := range match.Chat {
+		if _, err := tx.Exec(
+			`INSERT INTO chat (match_id, position, name, message, team, time) VALUES (?, ?, ?, ?, ?, ?)`,
+			id, position, message.Name, message.Text, message.Team, int64(message.Time),
+		); err != nil {
+			return false, err
+		}
+	}
+	for position, hit := range match.Hits {
+		if _, err := tx.Exec(
+			`INSERT INTO hits (match_id, position, attacker_id, victim_id, location, weapon, time)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, position, hit.AttackerID, hit.VictimID, hit.Location, hit.Weapon, int64(hit.Time),
+		); err != nil {
+			return false, err
+		}
+	}
+	for position, assist := range match.Assists {
+		if _, err := tx.Exec(
+			`INSERT INTO assists (match_id, position, assistant_id, killer_id, victim_id, time) VALUES (?, ?, ?, ?, ?, ?)`,
+			id, position, assist.AssistantID, assist.KillerID, assist.VictimID, int64(assist.Time),
+		); err != nil {
+			return false, err
+		}
+	}
+	for position, stats := range match.PlayerStats {
+		if _, err := tx.Exec(
+			`INSERT INTO player_stats (match_id, position, client_id, name, steam_id, score, kills, deaths,
+				damage_dealt, damage_taken, play_time, time)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, position, stats.PlayerID, stats.Name, stats.SteamID, stats.Score, stats.Kills, stats.Deaths,
+			stats.DamageDealt, stats.DamageTaken, int64(stats.PlayTime), int64(stats.Time),
+		); err != nil {
+			return false, err
+		}
+	}
+	if stats := match.MatchStats; stats != nil {
+		if _, err := tx.Exec(
+			`INSERT INTO match_stats (match_id, exit_message, length, aborted, red_score, blue_score)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			id, stats.ExitMessage, int64(stats.Length), stats.Aborted, stats.RedScore, stats.BlueScore,
+		); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// Matches returns every match in the database, ordered by source and
+// by their position in it.
+func (s *Store) Matches() ([]Match, error) {
+	rows, err := s.db.Query(`SELECT id, source, position, start_time, end_time FROM matches ORDER BY source, position`)
+	if err != nil {
+		return nil, err
+	}
+	matches := []Match{}
+	for rows.Next() {
+		var m Match
+		var startTime, endTime int64
+		if err := rows.Scan(&m.ID, &m.Source, &m.Index, &startTime, &endTime); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Match = parser.Match{
+			Players:   []parser.Player{},
+			Events:    []parser.Kill{},
+			StartTime: time.Duration(startTime),
+			EndTime:   time.Duration(endTime),
+			Settings:  map[string]string{},
+		}
+		matches = append(matches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range matches {
+		if err := s.loadMatch(&matches[i]); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+func (s *Store) loadMatch(m *Match) error {
+	rows, err := s.db.Query(`SELECT key, value FROM settings WHERE match_id = ?`, m.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		m.Match.Settings[key] = value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.Query(
+		`SELECT sessions.client_id, players.name, sessions.guid, sessions.ip, sessions.bot,
+			sessions.connected, sessions.disconnected FROM sessions
+		JOIN players ON players.id = sessions.player_id
+		WHERE sessions.match_id = ? ORDER BY sessions.position`, m.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var player parser.Player
+		var connected, disconnected int64
+		if err := rows.Scan(&player.ID, &player.Name, &player.GUID, &player.IP, &player.IsBot, &connected, &disconnected); err != nil {
+			rows.Close()
+			return err
+		}
+		player.Connected = time.Duration(connected)
+		player.Disconnected = time.Duration(disconnected)
+		m.Match.Players = append(m.Match.Players, player)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.Query(
+		`SELECT killer_id, victim_id, mean_of_death, weapon, time FROM kills
+		WHERE match_id = ? ORDER BY position`, m.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var kill parser.Kill
+		var killTime int64
+		if err := rows.Scan(&kill.KillerID, &kill.VictimID, &kill.MeanOfDeath, &kill.Weapon, &killTime); err != nil {
+			rows.Close()
+			return err
+		}
+		kill.Time = time.Duration(killTime)
+		m.Match.Events = append(m.Match.Events, kill)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.Query(`SELECT client_id, item, time FROM items WHERE match_id = ? ORDER BY position`, m.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var item parser.Item
+		var itemTime int64
+		if err := rows.Scan(&item.PlayerID, &item.Name, &itemTime); err != nil {
+			rows.Close()
+			return err
+		}
+		item.Time = time.Duration(itemTime)
+		m.Match.Items = append(m.Match.Items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.Query(`SELECT name, message, team, time FROM chat WHERE match_id = ? ORDER BY position`, m.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var message parser.Message
+		var messageTime int64
+		if err := rows.Scan(&message.Name, &message.Text, &message.Team, &messageTime); err != nil {
+			rows.Close()
+			return err
+		}
+		message.Time = time.Duration(messageTime)
+		m.Match.Chat = append(m.Match.Chat, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.Query(`SELECT attacker_id, victim_id, location, weapon, time FROM hits
+		WHERE match_id = ? ORDER BY position`, m.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var hit parser.Hit
+		var hitTime int64
+		if err := rows.Scan(&hit.AttackerID, &hit.VictimID, &hit.Location, &hit.Weapon, &hitTime); err != nil {
+			rows.Close()
+			return err
+		}
+		hit.Time = time.Duration(hitTime)
+		m.Match.Hits = append(m.Match.Hits, hit)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.Query(`SELECT assistant_id, killer_id, victim_id, time FROM assists
+		WHERE match_id = ? ORDER BY position`, m.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var assist parser.Assist
+		var assistTime int64
+		if err := rows.Scan(&assist.AssistantID, &assist.KillerID, &assist.VictimID, &assistTime); err != nil {
+			rows.Close()
+			return err
+		}
+		assist.Time = time.Duration(assistTime)
+		m.Match.Assists = append(m.Match.Assists, assist)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.Query(`SELECT client_id, name, steam_id, score, kills, deaths, damage_dealt, damage_taken,
+		play_time, time FROM player_stats WHERE match_id = ? ORDER BY position`, m.ID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var stats parser.PlayerStats
+		var playTime, statsTime int64
+		if err := rows.Scan(&stats.PlayerID, &stats.Name, &stats.SteamID, &stats.Score, &stats.Kills, &stats.Deaths,
+			&stats.DamageDealt, &stats.DamageTaken, &playTime, &statsTime); err != nil {
+			rows.Close()
+			return err
+		}
+		stats.PlayTime = time.Duration(playTime)
+		stats.Time = time.Duration(statsTime)
+		m.Match.PlayerStats = append(m.Match.PlayerStats, stats)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var stats parser.MatchStats
+	var length int64
+	err = s.db.QueryRow(`SELECT exit_message, length, aborted, red_score, blue_score FROM match_stats
+		WHERE match_id = ?`, m.ID).Scan(&stats.ExitMessage, &length, &stats.Aborted, &stats.RedScore, &stats.BlueScore)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	stats.Length = time.Duration(length)
+	m.Match.MatchStats = &stats
+	return nil
+}
+
+// Checkpoint returns the checkpoint saved for the source log file, or a
+// zero checkpoint when there is none.
+func (s *Store) Checkpoint(source string) (checkpoint.Checkpoint, error) {
+	var cp checkpoint.Checkpoint
+	var content string
+	err := s.db.QueryRow(`SELECT checkpoint FROM checkpoints WHERE source = ?`, source).Scan(&content)
+	if err == sql.ErrNoRows {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	return cp, json.Unmarshal([]byte(content), &cp)
+}
+
+// SaveCheckpoint saves the checkpoint of the source log file, replacing
+// the previous one.
+func (s *Store) SaveCheckpoint(source string, cp checkpoint.Checkpoint) error {
+	content, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT OR REPLACE INTO checkpoints (source, checkpoint, updated_at) VALUES (?, ?, ?)`,
+		source, string(content), time.Now().UTC(),
+	)
+	return err
+}
@@ -0,0 +1,212 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/checkpoint"
+	"github.com/reesilva/quake-log/pkg/output"
+	"github.com/reesilva/quake-log/pkg/parser"
+	"github.com/reesilva/quake-log/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var storeMatches = []parser.Match{
+	{
+		Players: []parser.Player{
+			{
+				ID:        2,
+				Name:      "Isgalamido",
+				Connected: 20*time.Minute + 38*time.Second,
+			},
+			{
+				ID:           3,
+				Name:         "Mocinha",
+				GUID:         "8A9F03B1C2D4E5F6",
+				IP:           "10.0.0.7",
+				Connected:    20*time.Minute + 40*time.Second,
+				Disconnected: 21*time.Minute + 50*time.Second,
+			},
+		},
+		Events: []parser.Kill{
+			{
+				KillerID:    2,
+				VictimID:    3,
+				MeanOfDeath: 10,
+				Time:        21*time.Minute + 10*time.Second,
+			},
+			{
+				KillerID:    1022,
+				VictimID:    2,
+				MeanOfDeath: 22,
+				Time:        21*time.Minute + 42*time.Second,
+			},
+		},
+		Items: []parser.Item{
+			{PlayerID: 2, Name: "weapon_railgun", Time: 20*time.Minute + 50*time.Second},
+		},
+		Chat: []parser.Message{
+			{Name: "Isgalamido", Text: "gg", Time: 22*time.Minute + 5*time.Second},
+			{Name: "Mocinha", Text: "rematch?", Team: true, Time: 22*time.Minute + 6*time.Second},
+		},
+		StartTime: 20*time.Minute + 37*time.Second,
+		EndTime:   22*time.Minute + 6*time.Second,
+		Settings: map[string]string{
+			"mapname":     "q3dm17",
+			"sv_hostname": "Code Miner Server",
+		},
+	},
+	{
+		Players: []parser.Player{
+			{
+				ID:   2,
+				Name: "Isgalamido",
+			},
+			{
+				ID:    4,
+				Name:  "Sarge",
+				IsBot: true,
+			},
+		},
+		Events: []parser.Kill{
+			{
+				KillerID:    2,
+				VictimID:    4,
+				MeanOfDeath: 19,
+				Weapon:      "UT_MOD_LR300",
+				Time:        25*time.Minute + 10*time.Second,
+			},
+		},
+		Hits: []parser.Hit{
+			{AttackerID: 2, VictimID: 4, Location: 1, Weapon: 19, Time: 25*time.Minute + 9*time.Second},
+		},
+		Assists: []parser.Assist{
+			{AssistantID: 4, KillerID: 2, VictimID: 4, Time: 25*time.Minute + 10*time.Second},
+		},
+		PlayerStats: []parser.PlayerStats{
+			{
+				PlayerID:    2,
+				Name:        "Isgalamido",
+				SteamID:     "76561198000000000",
+				Score:       1,
+				Kills:       1,
+				DamageDealt: 100,
+				PlayTime:    30 * time.Second,
+				Time:        25*time.Minute + 30*time.Second,
+			},
+		},
+		MatchStats: &parser.MatchStats{ExitMessage: "Fraglimit hit.", Length: 30 * time.Second, RedScore: 1},
+		StartTime:  25 * time.Minute,
+		Settings:   map[string]string{"mapname": "q3dm6"},
+	},
+}
+
+func openStore(t *testing.T) (*store.Store, string, func()) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "quake.db")
+	s, err := store.Open(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return s, path, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSaveMatch(t *testing.T) {
+	s, path, cleanup := openStore(t)
+	defer cleanup()
+
+	for key, match := range storeMatches {
+		saved, err := s.SaveMatch("games.log", key+1, match)
+		assert.NoError(t, err)
+		assert.True(t, saved)
+	}
+	saved, err := s.SaveMatch("games.log", 1, storeMatches[0])
+	assert.NoError(t, err)
+	assert.False(t, saved)
+	saved, err = s.SaveMatch("games.log", 3, storeMatches[0])
+	assert.NoError(t, err)
+	assert.True(t, saved)
+	ids := output.MatchIDs{}
+	ids.ID(1, storeMatches[0])
+	third := ids.ID(3, storeMatches[0])
+
+	assert.NoError(t, s.Close())
+	s, err = store.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	saved, err = s.SaveMatch("games.log", 2, storeMatches[1])
+	assert.NoError(t, err)
+	assert.False(t, saved)
+	saved, err = s.SaveMatch("/var/log/games.log", 1, storeMatches[1])
+	assert.NoError(t, err)
+	assert.False(t, saved)
+
+	matches, err := s.Matches()
+	assert.NoError(t, err)
+	assert.Equal(t, []store.Match{
+		{
+			ID:     output.MatchID(storeMatches[0]),
+			Source: "games.log",
+			Index:  1,
+			Match:  storeMatches[0],
+		},
+		{
+			ID:     output.MatchID(storeMatches[1]),
+			Source: "games.log",
+			Index:  2,
+			Match:  storeMatches[1],
+		},
+		{
+			ID:     third,
+			Source: "games.log",
+			Index:  3,
+			Match:  storeMatches[0],
+		},
+	}, matches)
+}
+
+func TestMatchesEmpty(t *testing.T) {
+	s, _, cleanup := openStore(t)
+	defer cleanup()
+
+	matches, err := s.Matches()
+	assert.NoError(t, err)
+	assert.Equal(t, []store.Match{}, matches)
+}
+
+func TestCheckpoint(t *testing.T) {
+	s, _, cleanup := openStore(t)
+	defer cleanup()
+
+	cp, err := s.Checkpoint("games.log")
+	assert.NoError(t, err)
+	assert.Equal(t, checkpoint.Checkpoint{}, cp)
+
+	saved := checkpoint.Checkpoint{
+		Offset:      128,
+		Fingerprint: "abc",
+		State: parser.StreamState{
+			Count: 2,
+			Match: &storeMatches[1],
+		},
+	}
+	assert.NoError(t, s.SaveCheckpoint("games.log", saved))
+	saved.Offset = 256
+	assert.NoError(t, s.SaveCheckpoint("games.log", saved))
+
+	cp, err = s.Checkpoint("games.log")
+	assert.NoError(t, err)
+	assert.Equal(t, saved, cp)
+}
@@ -0,0 +1,143 @@
+// Package tail follows a growing log file, like tail -F, surviving
+// log rotation and truncation.
+package tail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultPollInterval is used when Options.PollInterval is not set.
+const DefaultPollInterval = 250 * time.Millisecond
+
+// Options configures how a file is followed.
+type Options struct {
+	// PollInterval is how long to wait for new data once the end of
+	// the file is reached.
+	PollInterval time.Duration
+	// FromEnd skips the content already in the file when it is opened
+	// for the first time.
+	FromEnd bool
+	// OnIdle, if set, is called every time the end of the file is
+	// reached, which means everything written so far was handled.
+	OnIdle func() error
+	// OnProgress, if set, receives the offset in the file followed of the
+	// next byte to be read every time it changes.
+	OnProgress func(offset int64)
+}
+
+// Follow reads path line by line calling handle for every complete
+// line, without the trailing new line. When the file is rotated the
+// rest of the old file is read and then the new one is followed from
+// its start, and when it's truncated it's read again from the start.
+// It only returns when ctx is done, with nil, or when reading the file,
+// handle or OnIdle fail.
+func Follow(ctx context.Context, path string, opts Options, handle func(line string) error) error {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	file, err := waitOpen(ctx, path, interval)
+	if file == nil {
+		return err
+	}
+	defer func() { file.Close() }()
+	var offset int64
+	if opts.FromEnd {
+		if offset, err = file.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+	reader := bufio.NewReader(file)
+	partial := ""
+	progress(opts, offset)
+
+	for {
+		chunk, err := reader.ReadString('\n')
+		offset += int64(len(chunk))
+		if err == nil {
+			line := partial + chunk[:len(chunk)-1]
+			partial = ""
+			if len(line) > 0 && line[len(line)-1] == '\r' {
+				line = line[:len(line)-1]
+			}
+			if err := handle(line); err != nil {
+				return err
+			}
+			progress(opts, offset)
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		partial += chunk
+
+		if opts.OnIdle != nil {
+			if err := opts.OnIdle(); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+
+		current, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		latest, err := os.Stat(path)
+		switch {
+		case err == nil && !os.SameFile(current, latest):
+			// Rotated: only switch once everything left in the old file was read.
+			if current.Size() > offset {
+				continue
+			}
+			next, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			file.Close()
+			file, offset, partial = next, 0, ""
+			reader.Reset(file)
+			progress(opts, offset)
+		case current.Size() < offset:
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			offset, partial = 0, ""
+			reader.Reset(file)
+			progress(opts, offset)
+		}
+	}
+}
+
+func progress(opts Options, offset int64) {
+	if opts.OnProgress != nil {
+		opts.OnProgress(offset)
+	}
+}
+
+// waitOpen opens path, waiting for it to be created if needed. It
+// returns a nil file when ctx is done before that.
+func waitOpen(ctx context.Context, path string, interval time.Duration) (*os.File, error) {
+	for {
+		file, err := os.Open(path)
+		if err == nil {
+			return file, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package tail_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reesilva/quake-log/pkg/tail"
+	"github.com/stretchr/testify/assert"
+)
+
+func appendLines(t *testing.T, path string, content string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func follow(t *testing.T, path string, opts tail.Options) (<-chan string, context.CancelFunc, <-chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan string, 100)
+	done := make(chan error, 1)
+	opts.PollInterval = 10 * time.Millisecond
+	go func() {
+		done <- tail.Follow(ctx, path, opts, func(line string) error {
+			lines <- line
+			return nil
+		})
+	}()
+	return lines, cancel, done
+}
+
+func expectLines(t *testing.T, lines <-chan string, want ...string) {
+	got := []string{}
+	for range want {
+		select {
+		case line := <-lines:
+			got = append(got, line)
+		case <-time.After(2 * time.Second):
+			assert.Equal(t, want, got)
+			t.FailNow()
+		}
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestFollow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("Reads existing and appended lines", func(t *testing.T) {
+		path := filepath.Join(dir, "append.log")
+		appendLines(t, path, "  0:00 InitGame: \\mapname\\q3dm17\n")
+		lines, cancel, done := follow(t, path, tail.Options{})
+		expectLines(t, lines, `  0:00 InitGame: \mapname\q3dm17`)
+		appendLines(t, path, " 20:34 Client")
+		appendLines(t, path, "Connect: 2\r\n")
+		expectLines(t, lines, " 20:34 ClientConnect: 2")
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("Skips existing lines from end", func(t *testing.T) {
+		path := filepath.Join(dir, "end.log")
+		appendLines(t, path, "old\n")
+		idle := make(chan struct{}, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		lines := make(chan string, 10)
+		go func() {
+			_ = tail.Follow(ctx, path, tail.Options{
+				PollInterval: 10 * time.Millisecond,
+				FromEnd:      true,
+				OnIdle: func() error {
+					select {
+					case idle <- struct{}{}:
+					default:
+					}
+					return nil
+				},
+			}, func(line string) error {
+				lines <- line
+				return nil
+			})
+		}()
+		<-idle
+		appendLines(t, path, "new\n")
+		expectLines(t, lines, "new")
+		cancel()
+	})
+
+	t.Run("Reports progress", func(t *testing.T) {
+		path := filepath.Join(dir, "progress.log")
+		appendLines(t, path, "first\nsecond\n")
+		offsets := make(chan int64, 10)
+		lines, cancel, done := follow(t, path, tail.Options{
+			OnProgress: func(offset int64) {
+				offsets <- offset
+			},
+		})
+		expectLines(t, lines, "first", "second")
+		cancel()
+		assert.NoError(t, <-done)
+		assert.Equal(t, []int64{0, 6, 13}, []int64{<-offsets, <-offsets, <-offsets})
+	})
+
+	t.Run("Waits for the file to be created", func(t *testing.T) {
+		path := filepath.Join(dir, "later.log")
+		lines, cancel, done := follow(t, path, tail.Options{})
+		time.Sleep(30 * time.Millisecond)
+		appendLines(t, path, "first\n")
+		expectLines(t, lines, "first")
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("Survives rotation", func(t *testing.T) {
+		path := filepath.Join(dir, "rotate.log")
+		appendLines(t, path, "first\n")
+		lines, cancel, done := follow(t, path, tail.Options{})
+		expectLines(t, lines, "first")
+		if err := os.Rename(path, path+".1"); err != nil {
+			t.Fatal(err)
+		}
+		appendLines(t, path+".1", "second\n")
+		appendLines(t, path, "third\n")
+		expectLines(t, lines, "second", "third")
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("Survives truncation", func(t *testing.T) {
+		path := filepath.Join(dir, "truncate.log")
+		appendLines(t, path, "first line\n")
+		lines, cancel, done := follow(t, path, tail.Options{})
+		expectLines(t, lines, "first line")
+		time.Sleep(30 * time.Millisecond)
+		if err := os.Truncate(path, 0); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(30 * time.Millisecond)
+		appendLines(t, path, "new\n")
+		expectLines(t, lines, "new")
+		cancel()
+		assert.NoError(t, <-done)
+	})
+}